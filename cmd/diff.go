@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	gitanimate "github.com/Xavier-Maruff/gitanimate/pkg"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old_file> <new_file> [flags]",
+	Short: "Animate the changes between two files",
+	Args:  cobra.ExactArgs(2),
+	Run:   runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	animParams := parseParams(cmd)
+	showWindow, _ := cmd.Flags().GetBool("show")
+
+	f, err := gitanimate.ReadFilePair(args[0], args[1])
+	if err != nil {
+		gitanimate.Logger.Fatalf("Failed to read files: %v", err)
+	}
+	useEditorConfig(animParams, filepath.Dir(args[1]))
+
+	animateSource(gitanimate.NewFilesSource("", []*gitanimate.CommitFile{f}), animParams, showWindow)
+
+	gitanimate.Logger.Infof("Diff processed")
+}
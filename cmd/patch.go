@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	gitanimate "github.com/Xavier-Maruff/gitanimate/pkg"
+	"github.com/spf13/cobra"
+)
+
+var patchCmd = &cobra.Command{
+	Use:   "patch <file.patch>... [flags]",
+	Short: "Animate the changes in a unified diff, or a series of them applied in order",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runPatch,
+}
+
+func init() {
+	patchCmd.Flags().StringP("dir", "d", "", "Directory containing the original files the patches apply to")
+	rootCmd.AddCommand(patchCmd)
+}
+
+func runPatch(cmd *cobra.Command, args []string) {
+	animParams := parseParams(cmd)
+	showWindow, _ := cmd.Flags().GetBool("show")
+	baseDir, _ := cmd.Flags().GetString("dir")
+	if baseDir != "" {
+		useEditorConfig(animParams, baseDir)
+	}
+
+	if len(args) == 1 {
+		files, err := gitanimate.ReadPatchFile(args[0], baseDir)
+		if err != nil {
+			gitanimate.Logger.Fatalf("Failed to read patch: %v", err)
+		}
+
+		animateSource(gitanimate.NewFilesSource("", files), animParams, showWindow)
+	} else {
+		animateSource(gitanimate.NewPatchSeriesSource(args, baseDir), animParams, showWindow)
+	}
+
+	gitanimate.Logger.Infof("Patches processed")
+}
@@ -1,19 +1,23 @@
 package cmd
 
 import (
+	"io"
 	"os"
 	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	gitanimate "github.com/Xavier-Maruff/gitanimate/pkg"
-	"github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "gitanimate <repo_path> [flags]",
 	Short: "Create typewriter animations from git repos",
 	Long:  ``,
+	Args:  cobra.ArbitraryArgs,
 	Run:   runGitAnimate,
 }
 
@@ -25,18 +29,63 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.Flags().StringP("output", "o", "gitanimate_output", "Path to output directory")
-	rootCmd.Flags().StringP("font", "f", "default", "Font to use")
-	rootCmd.Flags().StringP("theme", "t", "catppuccin-mocha", "Chroma theme used for syntax highlighting")
-	rootCmd.Flags().Float32P("max_delay", "s", 0.5, "Maximum delay between edits")
-	rootCmd.Flags().Float32P("min_delay", "i", 0.01, "Minimum delay between edits")
-	rootCmd.Flags().BoolP("disable_random", "r", false, "Disable delay randomisation between edits")
+	rootCmd.PersistentFlags().StringP("output", "o", "gitanimate_output", "Path to output directory")
+	rootCmd.PersistentFlags().StringP("font", "f", "default", "Font to use")
+	rootCmd.PersistentFlags().StringSlice("font_fallback", nil, "Fonts to draw glyphs the main font is missing, in order")
+	rootCmd.PersistentFlags().String("font_bold", "", "Bold font file, bold tokens are emboldened synthetically without one")
+	rootCmd.PersistentFlags().String("font_italic", "", "Italic font file, italic tokens stay upright without one")
+	rootCmd.PersistentFlags().String("font_bold_italic", "", "Bold italic font file")
+	rootCmd.PersistentFlags().StringP("theme", "t", "catppuccin-mocha", "Chroma theme name, or a chroma XML/JSON style or VS Code theme file")
+	rootCmd.PersistentFlags().String("gutter_color", "", "Hex colour behind the line numbers, overrides the theme")
+	rootCmd.PersistentFlags().String("line_number_color", "", "Hex colour of the line numbers, overrides the theme")
+	rootCmd.PersistentFlags().String("cursor_color", "", "Hex colour of the cursor, overrides the theme")
+	rootCmd.PersistentFlags().String("selection_color", "", "Hex colour of selections, overrides the theme")
+	rootCmd.PersistentFlags().Float32P("max_delay", "s", 0.5, "Maximum delay between edits")
+	rootCmd.PersistentFlags().Float32P("min_delay", "i", 0.01, "Minimum delay between edits")
+	rootCmd.PersistentFlags().BoolP("disable_random", "r", false, "Disable delay randomisation between edits")
 	rootCmd.Flags().StringP("start", "a", "initial", "Commit to start from")
 	rootCmd.Flags().StringP("end", "e", "", "Commit to end at")
 	rootCmd.Flags().Int32P("max_commits", "m", 0, "Maximum number of commits to process")
-	rootCmd.Flags().BoolP("show", "w", false, "Show the animation as it is created")
-	rootCmd.Flags().Int32P("width", "x", 750, "Width of the output")
-	rootCmd.Flags().Int32P("height", "y", 800, "Height of the output")
+	rootCmd.PersistentFlags().BoolP("show", "w", false, "Show the animation as it is created")
+	rootCmd.PersistentFlags().Int32P("width", "x", 750, "Width of the output")
+	rootCmd.PersistentFlags().Int32P("height", "y", 800, "Height of the output")
+	rootCmd.PersistentFlags().String("resolution", "", "Output size preset: 1080p, 4k, square or vertical, overrides width and height")
+	rootCmd.PersistentFlags().Float32("scale", 1, "Render at this multiple of the output size and scale frames down, for smoother text")
+	rootCmd.PersistentFlags().String("diff_mode", gitanimate.DiffModeChar, "Diff granularity: line, word or char")
+	rootCmd.PersistentFlags().Float32("nav_speed", 10, "Cursor navigation steps per second between edits, 0 to jump straight there")
+	rootCmd.PersistentFlags().Int64("seed", 0, "Seed for the typing model, 0 picks a random one")
+	rootCmd.PersistentFlags().String("typing_dist", gitanimate.TypingDistExp, "Inter-key delay distribution: exp, lognormal or uniform")
+	rootCmd.PersistentFlags().Float32("word_pause", 0.15, "Average extra pause in seconds before starting a word")
+	rootCmd.PersistentFlags().Float32("typo_rate", 0.02, "Chance of mistyping a letter and backspacing it")
+	rootCmd.PersistentFlags().Float32("burst", 0.5, "Delay multiplier for common bigrams typed in a burst")
+	rootCmd.PersistentFlags().Int("paste_lines", 30, "Paste inserts of at least this many lines instead of typing them, 0 to always type")
+	rootCmd.PersistentFlags().String("delete_style", gitanimate.DeleteStyleSelect, "How deletions are shown: select, strike or backspace")
+	rootCmd.PersistentFlags().Float32("summary", 0, "Seconds to hold a final frame highlighting every changed line, 0 to skip")
+	rootCmd.PersistentFlags().String("view", gitanimate.ViewType, "Render mode: type, or split/unified for a diff view scrolling through each hunk")
+	rootCmd.PersistentFlags().String("wrap", gitanimate.WrapOn, "Long lines: on to wrap with indented continuation rows, off to pan horizontally with the cursor")
+	rootCmd.PersistentFlags().Float32("establish", 0, "Seconds of an establishing shot zooming in from an overview of the file, 0 to open directly on the first edit")
+	rootCmd.PersistentFlags().Int("fold", 0, "Fold unchanged lines more than N lines away from any edit into a marker, 0 to show the whole file")
+	rootCmd.PersistentFlags().Float32("font_size", gitanimate.DefaultFontSize, "Font size in pixels")
+	rootCmd.PersistentFlags().Float32("line_height", gitanimate.DefaultLineHeight, "Line height as a multiple of the font size")
+	rootCmd.PersistentFlags().Float32("padding", gitanimate.DefaultPadding, "Padding in pixels around the code")
+	rootCmd.PersistentFlags().Int("tab_width", 0, "Columns per tab stop, 0 to use the repository's .editorconfig or 4")
+	rootCmd.PersistentFlags().Bool("show_whitespace", false, "Mark spaces and tabs with faint dots and arrows")
+	rootCmd.PersistentFlags().String("chrome", gitanimate.ChromeNone, "Frame around the code: none, window, terminal or vscode")
+	rootCmd.PersistentFlags().Float32("chrome_padding", gitanimate.DefaultChromePadding, "Pixels of background around the chrome window")
+	rootCmd.PersistentFlags().StringSlice("chrome_background", nil, "One hex colour, or two for a gradient, behind the chrome window")
+	rootCmd.PersistentFlags().StringArray("terminal_before", nil, "Command typed into a terminal before each file's clip, repeatable, {file}, {hash} and {subject} are filled in")
+	rootCmd.PersistentFlags().StringArray("terminal_after", nil, "Command typed into a terminal after each file's clip, repeatable")
+	rootCmd.PersistentFlags().String("terminal_prompt", gitanimate.DefaultTerminalPrompt, "Shell prompt shown in front of terminal commands")
+	rootCmd.PersistentFlags().Bool("commit_captions", false, "Show each commit's subject as a lower third caption during its clips")
+	rootCmd.PersistentFlags().String("captions", "", "YAML or JSON caption track, a list of {commit, file, text, at, for} cues")
+	rootCmd.PersistentFlags().String("subtitles", "", "Also write the captions next to each clip as srt or vtt subtitles")
+	rootCmd.PersistentFlags().Bool("minimap", false, "Show a minimap of the whole file with the changes and the visible part marked")
+	rootCmd.PersistentFlags().Int("fit_width", 0, "Pick the font size that fits N columns across the output, overrides font_size")
+
+	//accept --diff-mode as well as --diff_mode
+	rootCmd.SetGlobalNormalizationFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		return pflag.NormalizedName(strings.ReplaceAll(name, "-", "_"))
+	})
 }
 
 func runGitAnimate(cmd *cobra.Command, args []string) {
@@ -52,81 +101,217 @@ func runGitAnimate(cmd *cobra.Command, args []string) {
 
 	animParams := parseParams(cmd)
 	repoPath := args[0]
+	useEditorConfig(animParams, repoPath)
 
 	start, _ := cmd.Flags().GetString("start")
 	end, _ := cmd.Flags().GetString("end")
 	maxCommits, _ := cmd.Flags().GetInt32("max_commits")
 	showWindow, _ := cmd.Flags().GetBool("show")
 
-	output := animParams.Output
-
 	gw, err := gitanimate.NewGitWrapper(repoPath, start, end)
 	if err != nil {
 		gitanimate.Logger.Fatalf("Failed to create GitWrapper: %v", err)
 	}
 
+	var src gitanimate.Source = gw
 	if maxCommits > 0 {
-		gw.Commits = gw.Commits[:maxCommits]
+		src = gitanimate.LimitSource(src, int(maxCommits))
 	}
 
-	i := 1
-	for {
-		gitanimate.Logger.Infof("Processing commit: %s (%d/%d)", gw.CurrCommit(), gw.Idx+1, len(gw.Commits))
-		animParams.Output = path.Join(output, strconv.Itoa(i)+"_"+gw.CurrCommit()[:12])
+	animateSource(src, animParams, showWindow)
 
-		files, err := gw.GetFiles()
+	gitanimate.Logger.Infof("All commits processed")
+}
+
+func animateSource(src gitanimate.Source, animParams *gitanimate.AnimateParams, showWindow bool) {
+	output := animParams.Output
+
+	for i := 1; ; i++ {
+		changeset, err := src.Next()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			gitanimate.Logger.Fatalf("Failed to get files from commit %s: %v", gw.CurrCommit(), err)
+			gitanimate.Logger.Fatalf("Failed to get changeset: %v", err)
 		}
 
-		for i, f := range files {
-			//gitanimate.Logger.Infof("\t(%d/%d) File: %s", i+1, len(files), f.FileName)
-
-			diffs := diffmatchpatch.New().DiffMain(f.PrevContent, f.CurrentContent, false)
-			diffs = diffmatchpatch.New().DiffCleanupSemanticLossless(diffs)
-			diffs = diffmatchpatch.New().DiffCleanupMerge(diffs)
-
-			err := gitanimate.AnimateDiff(&gitanimate.AnimateDiffParams{
-				Pos:         i + 1,
-				Total:       len(files),
-				Diffs:       diffs,
-				PrevContent: f.PrevContent,
-				Filename:    f.FileName,
-				Params:      animParams,
-				ShowWindow:  showWindow,
-			})
-			if err != nil {
-				gitanimate.Logger.Errorf("Failed to animate diff: %v", err)
-			}
+		animParams.Output = output
+		if changeset.ID != "" {
+			gitanimate.Logger.Infof("Processing changeset: %s (%d/%d)", changeset.ID, i, src.Len())
+			animParams.Output = path.Join(output, strconv.Itoa(i)+"_"+changeset.ID[:min(12, len(changeset.ID))])
 		}
 
-		_, err = gw.PopCommit()
+		animateFiles(changeset, animParams, showWindow)
+	}
+
+	animParams.Output = output
+}
+
+func animateFiles(changeset *gitanimate.Changeset, animParams *gitanimate.AnimateParams, showWindow bool) {
+	files := changeset.Files
+	for i, f := range files {
+		//gitanimate.Logger.Infof("\t(%d/%d) File: %s", i+1, len(files), f.FileName)
+
+		diffs, err := gitanimate.ComputeDiffs(f.PrevContent, f.CurrentContent, animParams.DiffMode)
 		if err != nil {
-			break
+			gitanimate.Logger.Fatalf("Failed to diff %s: %v", f.FileName, err)
+		}
+
+		err = gitanimate.AnimateDiff(&gitanimate.AnimateDiffParams{
+			Pos:         i + 1,
+			Total:       len(files),
+			Diffs:       diffs,
+			Segments:    f.Segments,
+			PrevContent: f.PrevContent,
+			Filename:    f.FileName,
+			Params:      animParams,
+			ShowWindow:  showWindow,
+			Commit:      changeset,
+		})
+		if err != nil {
+			gitanimate.Logger.Errorf("Failed to animate diff: %v", err)
 		}
 	}
+}
 
-	gitanimate.Logger.Infof("All commits processed")
+// useEditorConfig picks up the .editorconfig files in dir and its subdirectories
+func useEditorConfig(animParams *gitanimate.AnimateParams, dir string) {
+	cfg, err := gitanimate.LoadEditorConfig(dir)
+	if err != nil {
+		gitanimate.Logger.Errorf("Ignoring .editorconfig: %v", err)
+		return
+	}
+	animParams.EditorConfig = cfg
 }
 
 func parseParams(cmd *cobra.Command) *gitanimate.AnimateParams {
 	outputDir, _ := cmd.Flags().GetString("output")
 	font, _ := cmd.Flags().GetString("font")
+	fontFallback, _ := cmd.Flags().GetStringSlice("font_fallback")
+	fontBold, _ := cmd.Flags().GetString("font_bold")
+	fontItalic, _ := cmd.Flags().GetString("font_italic")
+	fontBoldItalic, _ := cmd.Flags().GetString("font_bold_italic")
 	theme, _ := cmd.Flags().GetString("theme")
+	gutterColor, _ := cmd.Flags().GetString("gutter_color")
+	lineNumberColor, _ := cmd.Flags().GetString("line_number_color")
+	cursorColor, _ := cmd.Flags().GetString("cursor_color")
+	selectionColor, _ := cmd.Flags().GetString("selection_color")
+	chrome, _ := cmd.Flags().GetString("chrome")
+	chromePadding, _ := cmd.Flags().GetFloat32("chrome_padding")
+	chromeBackground, _ := cmd.Flags().GetStringSlice("chrome_background")
+	terminalBefore, _ := cmd.Flags().GetStringArray("terminal_before")
+	terminalAfter, _ := cmd.Flags().GetStringArray("terminal_after")
+	terminalPrompt, _ := cmd.Flags().GetString("terminal_prompt")
+	commitCaptions, _ := cmd.Flags().GetBool("commit_captions")
+	captionsPath, _ := cmd.Flags().GetString("captions")
+	subtitles, _ := cmd.Flags().GetString("subtitles")
+	minimap, _ := cmd.Flags().GetBool("minimap")
 	minDelay, _ := cmd.Flags().GetFloat32("min_delay")
 	maxDelay, _ := cmd.Flags().GetFloat32("max_delay")
 	width, _ := cmd.Flags().GetInt32("width")
 	height, _ := cmd.Flags().GetInt32("height")
+	resolution, _ := cmd.Flags().GetString("resolution")
+	scale, _ := cmd.Flags().GetFloat32("scale")
 	disableRandom, _ := cmd.Flags().GetBool("disable_random")
+	diffMode, _ := cmd.Flags().GetString("diff_mode")
+	navSpeed, _ := cmd.Flags().GetFloat32("nav_speed")
+	seed, _ := cmd.Flags().GetInt64("seed")
+	typingDist, _ := cmd.Flags().GetString("typing_dist")
+	wordPause, _ := cmd.Flags().GetFloat32("word_pause")
+	typoRate, _ := cmd.Flags().GetFloat32("typo_rate")
+	burst, _ := cmd.Flags().GetFloat32("burst")
+	pasteLines, _ := cmd.Flags().GetInt("paste_lines")
+	deleteStyle, _ := cmd.Flags().GetString("delete_style")
+	summary, _ := cmd.Flags().GetFloat32("summary")
+	view, _ := cmd.Flags().GetString("view")
+	wrap, _ := cmd.Flags().GetString("wrap")
+	establish, _ := cmd.Flags().GetFloat32("establish")
+	fold, _ := cmd.Flags().GetInt("fold")
+	fontSize, _ := cmd.Flags().GetFloat32("font_size")
+	lineHeight, _ := cmd.Flags().GetFloat32("line_height")
+	padding, _ := cmd.Flags().GetFloat32("padding")
+	fitWidth, _ := cmd.Flags().GetInt("fit_width")
+	tabWidth, _ := cmd.Flags().GetInt("tab_width")
+	showWhitespace, _ := cmd.Flags().GetBool("show_whitespace")
 
-	return &gitanimate.AnimateParams{
-		Output:        outputDir,
-		Font:          font,
-		Theme:         theme,
-		MinDelay:      minDelay,
-		MaxDelay:      maxDelay,
-		Width:         width,
-		Height:        height,
-		DisableRandom: disableRandom,
+	if resolution != "" {
+		size, ok := gitanimate.Resolutions[resolution]
+		if !ok {
+			gitanimate.Logger.Fatalf("Unknown resolution %q, expected 1080p, 4k, square or vertical", resolution)
+		}
+		width, height = size[0], size[1]
+	}
+	//the window rounds odd sizes up for libx264, let the user know
+	if width%2 != 0 || height%2 != 0 {
+		gitanimate.Logger.Warnf("Rounding %dx%d up to even dimensions", width, height)
+	}
+
+	var captions *gitanimate.CaptionTrack
+	if captionsPath != "" {
+		var err error
+		captions, err = gitanimate.LoadCaptionTrack(captionsPath)
+		if err != nil {
+			gitanimate.Logger.Fatalf("Invalid caption track: %v", err)
+		}
+	}
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+		gitanimate.Logger.Infof("Using seed %d", seed)
+	}
+
+	params := &gitanimate.AnimateParams{
+		Output:           outputDir,
+		Font:             font,
+		Theme:            theme,
+		MinDelay:         minDelay,
+		MaxDelay:         maxDelay,
+		Width:            width,
+		Height:           height,
+		DisableRandom:    disableRandom,
+		DiffMode:         diffMode,
+		NavSpeed:         navSpeed,
+		Seed:             seed,
+		TypingDist:       typingDist,
+		WordPause:        wordPause,
+		TypoRate:         typoRate,
+		BurstFactor:      burst,
+		PasteLines:       pasteLines,
+		DeleteStyle:      deleteStyle,
+		Summary:          summary,
+		View:             view,
+		Wrap:             wrap,
+		Establish:        establish,
+		Fold:             fold,
+		FontFallback:     fontFallback,
+		FontBold:         fontBold,
+		FontItalic:       fontItalic,
+		FontBoldItalic:   fontBoldItalic,
+		FontSize:         fontSize,
+		LineHeight:       lineHeight,
+		Padding:          padding,
+		FitWidth:         fitWidth,
+		TabWidth:         tabWidth,
+		ShowWhitespace:   showWhitespace,
+		GutterColor:      gutterColor,
+		LineNumberColor:  lineNumberColor,
+		CursorColor:      cursorColor,
+		SelectionColor:   selectionColor,
+		Chrome:           chrome,
+		ChromePadding:    chromePadding,
+		ChromeBackground: chromeBackground,
+		TerminalBefore:   terminalBefore,
+		TerminalAfter:    terminalAfter,
+		TerminalPrompt:   terminalPrompt,
+		CommitCaptions:   commitCaptions,
+		Captions:         captions,
+		Subtitles:        subtitles,
+		Minimap:          minimap,
+		Scale:            scale,
+	}
+	//options with a fixed set of values are checked once here rather than on every clip
+	if err := gitanimate.CheckParams(params); err != nil {
+		gitanimate.Logger.Fatalf("Invalid parameters: %v", err)
 	}
+	return params
 }
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	gitanimate "github.com/Xavier-Maruff/gitanimate/pkg"
+	"github.com/spf13/cobra"
+)
+
+var scriptCmd = &cobra.Command{
+	Use:   "script <storyboard.yaml> [flags]",
+	Short: "Animate a storyboard of scripted edits (YAML or JSON)",
+	Args:  cobra.ExactArgs(1),
+	Run:   runScript,
+}
+
+func init() {
+	rootCmd.AddCommand(scriptCmd)
+}
+
+func runScript(cmd *cobra.Command, args []string) {
+	animParams := parseParams(cmd)
+	showWindow, _ := cmd.Flags().GetBool("show")
+
+	changeset, err := gitanimate.LoadStoryboard(args[0])
+	if err != nil {
+		gitanimate.Logger.Fatalf("Invalid storyboard: %v", err)
+	}
+
+	useEditorConfig(animParams, filepath.Dir(args[0]))
+
+	animateSource(gitanimate.NewFilesSource(changeset.ID, changeset.Files), animParams, showWindow)
+
+	gitanimate.Logger.Infof("Storyboard processed")
+}
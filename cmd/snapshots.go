@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	gitanimate "github.com/Xavier-Maruff/gitanimate/pkg"
+	"github.com/spf13/cobra"
+)
+
+var snapshotsCmd = &cobra.Command{
+	Use:   "snapshots <dir> [flags]",
+	Short: "Animate the changes between snapshot subdirectories of dir, in name order",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSnapshots,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotsCmd)
+}
+
+func runSnapshots(cmd *cobra.Command, args []string) {
+	animParams := parseParams(cmd)
+	showWindow, _ := cmd.Flags().GetBool("show")
+
+	src, err := gitanimate.NewSnapshotSource(args[0])
+	if err != nil {
+		gitanimate.Logger.Fatalf("Failed to read snapshots: %v", err)
+	}
+
+	useEditorConfig(animParams, args[0])
+
+	animateSource(src, animParams, showWindow)
+
+	gitanimate.Logger.Infof("All snapshots processed")
+}
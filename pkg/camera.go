@@ -0,0 +1,136 @@
+package gitanimate
+
+import (
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	//seconds the camera takes to roughly settle on a new target
+	cameraSmoothTime = 0.3
+	//fraction of the view kept between the cursor and the edges
+	cameraBand = 0.2
+	//fraction of the establishing shot spent on the overview before zooming in
+	establishHold = 0.35
+)
+
+// camera eases the scroll offset towards a target with a critically damped spring,
+// so short hops glide and long trips between edits accelerate then settle
+type camera struct {
+	X         float32
+	Y         float32
+	TargetX   float32
+	TargetY   float32
+	velocityX float32
+	velocityY float32
+}
+
+// follow retargets the camera so the cursor stays inside the comfortable band of the view
+func (c *camera) follow(cursorY, viewHeight, contentHeight float32) {
+	margin := viewHeight * cameraBand
+
+	if cursorY < c.TargetY+margin {
+		c.TargetY = cursorY - margin
+	} else if cursorY+lineHeight > c.TargetY+viewHeight-margin {
+		c.TargetY = cursorY + lineHeight - viewHeight + margin
+	}
+
+	c.TargetY = min(c.TargetY, max(contentHeight-viewHeight+margin, 0))
+	c.TargetY = max(c.TargetY, 0)
+}
+
+// followX pans horizontally to keep the cursor between left (where the text starts) and right
+func (c *camera) followX(cursorX, left, right float32) {
+	margin := (right - left) * cameraBand
+
+	if cursorX-c.TargetX < left+margin {
+		c.TargetX = cursorX - left - margin
+	} else if cursorX-c.TargetX > right-margin {
+		c.TargetX = cursorX - right + margin
+	}
+
+	c.TargetX = max(c.TargetX, 0)
+}
+
+// frame places the camera on pos with some context above it, as if the clip had already scrolled there
+func (c *camera) frame(text string, pos int, folds []fold, startY, viewHeight float32) {
+	starts := lineStarts(text)
+	line, _ := lineCol(starts, pos)
+	cursorY := startY + float32(line-hiddenLines(folds, pos))*lineHeight
+	contentHeight := startY + float32(len(starts)-hiddenLines(folds, len(text)))*lineHeight
+
+	c.TargetY = cursorY - viewHeight/3
+	c.follow(cursorY, viewHeight, contentHeight)
+	c.jump(c.TargetY)
+}
+
+func (c *camera) jump(y float32) {
+	c.Y = y
+	c.TargetY = y
+	c.velocityY = 0
+}
+
+func (c *camera) settled() bool {
+	diff := c.Y - c.TargetY
+	return diff*diff < 1 && c.velocityY*c.velocityY < 1
+}
+
+func (c *camera) update(deltaTime float32) {
+	c.X, c.velocityX = smoothDamp(c.X, c.TargetX, c.velocityX, deltaTime)
+	c.Y, c.velocityY = smoothDamp(c.Y, c.TargetY, c.velocityY, deltaTime)
+}
+
+func smoothDamp(pos, target, velocity, deltaTime float32) (float32, float32) {
+	omega := 2 / float32(cameraSmoothTime)
+	x := omega * deltaTime
+	exp := 1 / (1 + x + 0.48*x*x + 0.235*x*x*x)
+
+	change := pos - target
+	temp := (velocity + omega*change) * deltaTime
+	velocity = (velocity - omega*temp) * exp
+	return target + (change+temp)*exp, velocity
+}
+
+func easeInOut(t float32) float32 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - (-2*t+2)*(-2*t+2)/2
+}
+
+// firstEdit is the offset of the first change in diffs, 0 if there is none
+func firstEdit(diffs []diffmatchpatch.Diff) int {
+	pos := 0
+	for _, d := range diffs {
+		if d.Type != diffmatchpatch.DiffEqual {
+			return pos
+		}
+		pos += len(d.Text)
+	}
+	return 0
+}
+
+// renderEstablishingShot shows the whole file, then zooms into the camera's opening position
+func renderEstablishingShot(tokens []chroma.Token, text string, folds []fold, view viewport, duration float32, cam *camera, wrap bool, bg rl.Color, recorder *frameRecorder) {
+	contentHeight := view.Y*2 + float32(len(lineStarts(text))-hiddenLines(folds, len(text)))*lineHeight
+	overview := min(canvasHeight/contentHeight, 1)
+
+	var elapsed float32
+	for elapsed < duration && !rl.WindowShouldClose() && recorder.clipFrames() <= MaxFrameCount {
+		t := easeInOut(max(elapsed/duration-establishHold, 0) / (1 - establishHold))
+		shot := rl.Camera2D{
+			Target: rl.Vector2{X: cam.X * t, Y: cam.Y * t},
+			Zoom:   overview + (1-overview)*t,
+		}
+
+		beginFrame(bg)
+		beginCamera(shot)
+		renderTokensAll(tokens, view, false, rl.Vector2{}, wrap, -1, nil, folds, nil)
+		endCamera()
+		endFrame()
+		recorder.capture()
+
+		elapsed += rl.GetFrameTime()
+	}
+}
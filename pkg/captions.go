@@ -0,0 +1,208 @@
+package gitanimate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SubtitlesSRT = "srt"
+	SubtitlesVTT = "vtt"
+)
+
+const (
+	captionCentered = iota
+	captionLowerThird
+)
+
+type caption struct {
+	Text      string
+	Placement int
+}
+
+// CaptionCue is a user supplied caption, shown during the clips of a commit (matched by hash prefix),
+// of a file, or of a file in a commit. At and For are seconds into the clip, For 0 lasts to the end
+type CaptionCue struct {
+	Commit string  `yaml:"commit"`
+	File   string  `yaml:"file"`
+	Text   string  `yaml:"text"`
+	At     float32 `yaml:"at"`
+	For    float32 `yaml:"for"`
+}
+
+type CaptionTrack struct {
+	Cues []CaptionCue
+}
+
+// subtitleCue is a caption as it went into the video, in frames
+type subtitleCue struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// LoadCaptionTrack reads a YAML (or JSON) list of caption cues
+func LoadCaptionTrack(path string) (*CaptionTrack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read caption track: %v", err)
+	}
+
+	track := &CaptionTrack{}
+	if err := yaml.Unmarshal(data, &track.Cues); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i, cue := range track.Cues {
+		if cue.Text == "" {
+			return nil, fmt.Errorf("%s: caption %d has no text", path, i+1)
+		}
+		if cue.Commit == "" && cue.File == "" {
+			return nil, fmt.Errorf("%s: caption %d needs a commit or a file", path, i+1)
+		}
+	}
+	return track, nil
+}
+
+// clipCaptions are the captions that can show during a clip
+type clipCaptions struct {
+	Subject string
+	Cues    []CaptionCue
+}
+
+func newClipCaptions(params *AnimateDiffParams) clipCaptions {
+	var c clipCaptions
+	commit := ""
+	if params.Commit != nil {
+		commit = params.Commit.ID
+		if params.Params.CommitCaptions {
+			c.Subject, _, _ = strings.Cut(strings.TrimSpace(params.Commit.Message), "\n")
+		}
+	}
+
+	if params.Params.Captions == nil {
+		return c
+	}
+	for _, cue := range params.Params.Captions.Cues {
+		if cue.Commit != "" && (commit == "" || !strings.HasPrefix(commit, cue.Commit)) {
+			continue
+		}
+		if cue.File != "" && cue.File != params.Filename {
+			continue
+		}
+		c.Cues = append(c.Cues, cue)
+	}
+	return c
+}
+
+// at lists the captions showing t seconds into the clip, the commit subject first
+func (c clipCaptions) at(t float32) []caption {
+	captions := []caption{}
+	if c.Subject != "" {
+		captions = append(captions, caption{Text: c.Subject, Placement: captionLowerThird})
+	}
+	for _, cue := range c.Cues {
+		if t >= cue.At && (cue.For <= 0 || t < cue.At+cue.For) {
+			captions = append(captions, caption{Text: cue.Text})
+		}
+	}
+	return captions
+}
+
+func (c clipCaptions) text() string {
+	var b strings.Builder
+	b.WriteString(c.Subject)
+	for _, cue := range c.Cues {
+		b.WriteString(cue.Text)
+	}
+	return b.String()
+}
+
+// renderCaptions stacks captions up from the bottom of the frame, the first lowest. Lower thirds
+// sit on the left behind an accent bar, the rest are centered
+func renderCaptions(captions []caption) {
+	padding := fontSize / 2
+	y := canvasHeight - padding*3
+
+	for _, c := range captions {
+		width := measureText(c.Text)
+		y -= fontSize + padding*2
+
+		switch c.Placement {
+		case captionLowerThird:
+			x := padding * 2
+			rl.DrawRectangleRec(rl.Rectangle{X: x, Y: y, Width: width + padding*3, Height: fontSize + padding*2}, rl.Fade(rl.Black, 0.7))
+			rl.DrawRectangleRec(rl.Rectangle{X: x, Y: y, Width: padding / 2, Height: fontSize + padding*2}, getColorForTokenType(chroma.Keyword))
+			drawText(c.Text, x+padding*2, y+padding, rl.White)
+		default:
+			x := (canvasWidth - width) / 2
+			rl.DrawRectangleRounded(rl.Rectangle{
+				X:      x - padding,
+				Y:      y,
+				Width:  width + padding*2,
+				Height: fontSize + padding*2,
+			}, 0.3, 8, rl.Fade(rl.Black, 0.7))
+			drawText(c.Text, x, y+padding, rl.White)
+		}
+
+		y -= padding
+	}
+}
+
+// showCaptions notes the captions in the frame about to be captured, for the subtitle file
+func (f *frameRecorder) showCaptions(captions []caption) {
+	if f.subtitles == "" {
+		return
+	}
+	if f.openCues == nil {
+		f.openCues = map[string]int{}
+	}
+
+	for _, c := range captions {
+		//carry on the cue if it was showing in the previous frame
+		if i, ok := f.openCues[c.Text]; ok && f.cues[i].End == f.frameCount {
+			f.cues[i].End++
+			continue
+		}
+		f.openCues[c.Text] = len(f.cues)
+		f.cues = append(f.cues, subtitleCue{Text: c.Text, Start: f.frameCount, End: f.frameCount + 1})
+	}
+}
+
+// writeSubtitles saves the captions shown in the clip as an srt or vtt file
+func writeSubtitles(path, format string, cues []subtitleCue) error {
+	var b strings.Builder
+	if format == SubtitlesVTT {
+		b.WriteString("WEBVTT\n\n")
+	}
+
+	for i, cue := range cues {
+		if format == SubtitlesSRT {
+			fmt.Fprintf(&b, "%d\n", i+1)
+		}
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", subtitleTime(cue.Start, format), subtitleTime(cue.End, format), cue.Text)
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write subtitles: %v", err)
+	}
+	return nil
+}
+
+func subtitleTime(frame int, format string) string {
+	ms := frameMillis(frame, FrameRate)
+	sep := ","
+	if format == SubtitlesVTT {
+		sep = "."
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// frameMillis is when frame starts at fps, to the nearest millisecond
+func frameMillis(frame, fps int) int {
+	return (frame*1000 + fps/2) / fps
+}
@@ -0,0 +1,208 @@
+package gitanimate
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSubtitleTime(t *testing.T) {
+	tests := []struct {
+		frame  int
+		format string
+		want   string
+	}{
+		{0, SubtitlesSRT, "00:00:00,000"},
+		{1, SubtitlesSRT, "00:00:00,100"},
+		{1, SubtitlesVTT, "00:00:00.100"},
+		{25, SubtitlesSRT, "00:00:02,500"},
+		{599, SubtitlesSRT, "00:00:59,900"},
+		{600, SubtitlesVTT, "00:01:00.000"},
+		{36000, SubtitlesSRT, "01:00:00,000"},
+		{36000*2 + 600*3 + 45, SubtitlesVTT, "02:03:04.500"},
+		{36000 * 100, SubtitlesSRT, "100:00:00,000"},
+	}
+	for _, tt := range tests {
+		if got := subtitleTime(tt.frame, tt.format); got != tt.want {
+			t.Errorf("frame %d (%s): got %q, want %q", tt.frame, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFrameMillis(t *testing.T) {
+	tests := []struct {
+		frame, fps, want int
+	}{
+		{0, 30, 0},
+		{1, 30, 33},
+		{2, 30, 67},
+		{3, 30, 100},
+		{1, 24, 42},
+		{5, 24, 208},
+		{7, 10, 700},
+	}
+	for _, tt := range tests {
+		if got := frameMillis(tt.frame, tt.fps); got != tt.want {
+			t.Errorf("frame %d at %dfps: got %d, want %d", tt.frame, tt.fps, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSubtitles(t *testing.T) {
+	cues := []subtitleCue{
+		{Text: "fix the parser", Start: 0, End: 40},
+		{Text: "note", Start: 5, End: 12},
+		{Text: "note", Start: 36001, End: 36010},
+	}
+	tests := []struct {
+		format, want string
+	}{
+		{SubtitlesSRT, "1\n00:00:00,000 --> 00:00:04,000\nfix the parser\n\n" +
+			"2\n00:00:00,500 --> 00:00:01,200\nnote\n\n" +
+			"3\n01:00:00,100 --> 01:00:01,000\nnote\n\n"},
+		{SubtitlesVTT, "WEBVTT\n\n" +
+			"00:00:00.000 --> 00:00:04.000\nfix the parser\n\n" +
+			"00:00:00.500 --> 00:00:01.200\nnote\n\n" +
+			"01:00:00.100 --> 01:00:01.000\nnote\n\n"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "out."+tt.format)
+		if err := writeSubtitles(path, tt.format, cues); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+
+	if err := writeSubtitles(filepath.Join(t.TempDir(), "missing", "out.srt"), SubtitlesSRT, cues); err == nil {
+		t.Errorf("missing directory: expected an error")
+	}
+}
+
+func TestShowCaptions(t *testing.T) {
+	f := &frameRecorder{subtitles: SubtitlesSRT}
+	subject := caption{Text: "subject", Placement: captionLowerThird}
+	note := caption{Text: "note"}
+	frames := [][]caption{
+		{subject},
+		{subject, note},
+		{subject, note},
+		{subject},
+		{subject, note},
+		{note},
+	}
+	for _, captions := range frames {
+		f.showCaptions(captions)
+		f.frameCount++
+	}
+
+	//cues are in the order they first showed, a caption that comes back is a new cue
+	want := []subtitleCue{
+		{Text: "subject", Start: 0, End: 5},
+		{Text: "note", Start: 1, End: 3},
+		{Text: "note", Start: 4, End: 6},
+	}
+	if !slices.Equal(f.cues, want) {
+		t.Errorf("got %+v, want %+v", f.cues, want)
+	}
+
+	off := &frameRecorder{}
+	off.showCaptions([]caption{subject})
+	if len(off.cues) != 0 {
+		t.Errorf("subtitles off: got %+v, want no cues", off.cues)
+	}
+}
+
+func TestClipCaptionsAt(t *testing.T) {
+	c := clipCaptions{
+		Subject: "fix the parser",
+		Cues: []CaptionCue{
+			{Text: "first", At: 0, For: 2},
+			{Text: "second", At: 1},
+		},
+	}
+	tests := []struct {
+		t    float32
+		want []string
+	}{
+		{0, []string{"fix the parser", "first"}},
+		{1.5, []string{"fix the parser", "first", "second"}},
+		{2, []string{"fix the parser", "second"}},
+		{100, []string{"fix the parser", "second"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, caption := range c.at(tt.t) {
+			got = append(got, caption.Text)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("at %v: got %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCaptionTrack(t *testing.T) {
+	tests := []struct {
+		name, yaml string
+		want       []CaptionCue
+		wantErr    bool
+	}{
+		{
+			name: "cues",
+			yaml: `
+- commit: abc123
+  text: Fix the parser
+- file: pkg/render.go
+  text: "Render: now faster"
+  at: 1.5
+  for: 2
+- commit: def
+  file: main.go
+  text: both
+`,
+			want: []CaptionCue{
+				{Commit: "abc123", Text: "Fix the parser"},
+				{File: "pkg/render.go", Text: "Render: now faster", At: 1.5, For: 2},
+				{Commit: "def", File: "main.go", Text: "both"},
+			},
+		},
+		{
+			name: "json",
+			yaml: `[{"commit": "abc", "text": "hi", "at": 3}]`,
+			want: []CaptionCue{{Commit: "abc", Text: "hi", At: 3}},
+		},
+		{name: "no text", yaml: "- commit: abc\n", wantErr: true},
+		{name: "no commit or file", yaml: "- text: hi\n", wantErr: true},
+		{name: "not a list", yaml: "text: hi\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "captions.yaml")
+		if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+		track, err := LoadCaptionTrack(path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(track.Cues, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, track.Cues, tt.want)
+		}
+	}
+
+	if _, err := LoadCaptionTrack(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("missing file: expected an error")
+	}
+}
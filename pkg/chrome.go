@@ -0,0 +1,207 @@
+package gitanimate
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	ChromeNone     = "none"
+	ChromeWindow   = "window"
+	ChromeTerminal = "terminal"
+	ChromeVSCode   = "vscode"
+
+	DefaultChromePadding = 32
+)
+
+// chromePreset is how a chrome style frames the code
+type chromePreset struct {
+	TitleBar      float32
+	Radius        float32
+	TrafficLights bool
+	Gradient      [2]rl.Color
+}
+
+var chromePresets = map[string]chromePreset{
+	ChromeWindow: {
+		TitleBar:      36,
+		Radius:        10,
+		TrafficLights: true,
+		Gradient:      [2]rl.Color{{R: 0x45, G: 0x68, B: 0xdc, A: 255}, {R: 0xb0, G: 0x6a, B: 0xb3, A: 255}},
+	},
+	ChromeTerminal: {
+		TitleBar:      28,
+		Radius:        6,
+		TrafficLights: true,
+		Gradient:      [2]rl.Color{{R: 0x23, G: 0x25, B: 0x26, A: 255}, {R: 0x41, G: 0x43, B: 0x45, A: 255}},
+	},
+	ChromeVSCode: {
+		TitleBar: 36,
+		Radius:   8,
+		Gradient: [2]rl.Color{{R: 0x1f, G: 0x40, B: 0x37, A: 255}, {R: 0x4c, G: 0x9a, B: 0x8a, A: 255}},
+	},
+}
+
+var trafficLights = []rl.Color{
+	{R: 0xff, G: 0x5f, B: 0x56, A: 255},
+	{R: 0xff, G: 0xbd, B: 0x2e, A: 255},
+	{R: 0x27, G: 0xc9, B: 0x3f, A: 255},
+}
+
+// chrome is the frame composited around the code area of every captured frame, background
+// is the whole output with the code area left for the captured screen
+type chrome struct {
+	background *rl.Image
+	code       rl.Rectangle
+}
+
+func checkChrome(params *AnimateParams) error {
+	switch params.Chrome {
+	case ChromeNone, ChromeWindow, ChromeTerminal, ChromeVSCode:
+	case "":
+		params.Chrome = ChromeNone
+	default:
+		return fmt.Errorf("unknown chrome %q, expected one of %s, %s, %s or %s",
+			params.Chrome, ChromeNone, ChromeWindow, ChromeTerminal, ChromeVSCode)
+	}
+	return nil
+}
+
+// chromeTitle is the text in a clip's title bar, it has to be drawn by the clip's fonts
+func chromeTitle(params *AnimateDiffParams) string {
+	switch params.Params.Chrome {
+	case ChromeWindow:
+		return params.Filename
+	case ChromeTerminal:
+		return "bash — " + filepath.Dir(params.Filename)
+	case ChromeVSCode:
+		return filepath.Base(params.Filename)
+	}
+	return ""
+}
+
+// chromeLayout places the window inside the output and the code inside the window, the code
+// is inset by the corner radius so the rounded corners don't clip it
+func chromeLayout(params *AnimateParams) (window, code rl.Rectangle) {
+	width, height := float32(params.Width), float32(params.Height)
+	preset, ok := chromePresets[params.Chrome]
+	if !ok {
+		return rl.Rectangle{Width: width, Height: height}, rl.Rectangle{Width: width, Height: height}
+	}
+
+	margin := float32(DefaultChromePadding)
+	if params.ChromePadding >= 0 {
+		margin = params.ChromePadding
+	}
+	window = rl.Rectangle{X: margin, Y: margin, Width: width - margin*2, Height: height - margin*2}
+	//whole pixels, the code area is the canvas frames are drawn on
+	code = rl.Rectangle{
+		X:      float32(math.Floor(float64(window.X + preset.Radius))),
+		Y:      float32(math.Floor(float64(window.Y + preset.TitleBar))),
+		Width:  float32(max(math.Floor(float64(window.Width-preset.Radius*2)), 1)),
+		Height: float32(max(math.Floor(float64(window.Height-preset.TitleBar-preset.Radius)), 1)),
+	}
+	return window, code
+}
+
+// newChrome draws everything but the code: the gradient, the window's shadow, its body and title bar,
+// nil without a chrome preset
+func newChrome(params *AnimateParams, title string, bg rl.Color) *chrome {
+	preset, ok := chromePresets[params.Chrome]
+	if !ok {
+		return nil
+	}
+	window, code := chromeLayout(params)
+	width, height := int(params.Width), int(params.Height)
+
+	gradient := preset.Gradient
+	if colors := params.ChromeBackground; len(colors) > 0 {
+		for i := range gradient {
+			c, err := parseHexColor(colors[min(i, len(colors)-1)])
+			if err != nil {
+				Logger.Errorf("Ignoring chrome background: %v", err)
+				gradient = preset.Gradient
+				break
+			}
+			gradient[i] = c
+		}
+	}
+	img := rl.GenImageGradientLinear(width, height, 45, gradient[0], gradient[1])
+
+	//a blurred copy of the window, offset down, blended in under it
+	shadow := rl.GenImageColor(width, height, rl.Blank)
+	imageDrawRoundedRect(shadow, rl.Rectangle{X: window.X, Y: window.Y + 8, Width: window.Width, Height: window.Height}, preset.Radius, rl.Fade(rl.Black, 0.5))
+	rl.ImageBlurGaussian(shadow, 16)
+	full := rl.Rectangle{Width: float32(width), Height: float32(height)}
+	rl.ImageDraw(img, shadow, full, full, rl.White)
+	rl.UnloadImage(shadow)
+
+	imageDrawRoundedRect(img, window, preset.Radius, bg)
+	bar := rl.Rectangle{X: window.X, Y: window.Y, Width: window.Width, Height: preset.TitleBar}
+	titleSize := min(fontSize, preset.TitleBar*0.5)
+	titleColor := ui.LineNumber
+
+	switch params.Chrome {
+	case ChromeTerminal:
+		//a darker bar across the top, rounded only at the top
+		imageDrawRoundedRect(img, bar, preset.Radius, rl.ColorBrightness(bg, -0.3))
+		rl.ImageDrawRectangleRec(img, rl.Rectangle{X: bar.X, Y: bar.Y + preset.Radius, Width: bar.Width, Height: bar.Height - preset.Radius}, rl.ColorBrightness(bg, -0.3))
+	case ChromeVSCode:
+		//a tab strip with the file's tab in the editor's colour
+		strip := rl.ColorBrightness(bg, -0.25)
+		imageDrawRoundedRect(img, bar, preset.Radius, strip)
+		rl.ImageDrawRectangleRec(img, rl.Rectangle{X: bar.X, Y: bar.Y + preset.Radius, Width: bar.Width, Height: bar.Height - preset.Radius}, strip)
+		tabWidth := rl.MeasureTextEx(font, title, titleSize, 0).X + preset.TitleBar
+		rl.ImageDrawRectangleRec(img, rl.Rectangle{X: bar.X + preset.Radius, Y: bar.Y + 4, Width: tabWidth, Height: bar.Height - 4}, bg)
+		rl.ImageDrawRectangleRec(img, rl.Rectangle{X: bar.X + preset.Radius, Y: bar.Y + 4, Width: tabWidth, Height: 2}, getColorForTokenType(chroma.Keyword))
+		pos := rl.Vector2{X: bar.X + preset.Radius + preset.TitleBar/2, Y: bar.Y + 2 + (bar.Height-titleSize)/2}
+		rl.ImageDrawTextEx(img, pos, font, title, titleSize, 0, getColorForTokenType(chroma.Text))
+		title = ""
+	}
+
+	if preset.TrafficLights {
+		radius := preset.TitleBar / 6
+		for i, c := range trafficLights {
+			x := bar.X + preset.Radius + radius + float32(i)*radius*3.4
+			rl.ImageDrawCircle(img, int32(x), int32(bar.Y+bar.Height/2), int32(radius), c)
+		}
+	}
+	if title != "" {
+		size := rl.MeasureTextEx(font, title, titleSize, 0)
+		pos := rl.Vector2{X: bar.X + (bar.Width-size.X)/2, Y: bar.Y + (bar.Height-size.Y)/2}
+		rl.ImageDrawTextEx(img, pos, font, title, titleSize, 0, titleColor)
+	}
+
+	return &chrome{background: img, code: code}
+}
+
+// composite draws a captured frame into the chrome, returning a new image the size of the output
+func (c *chrome) composite(frame *rl.Image) *rl.Image {
+	img := rl.ImageCopy(c.background)
+	src := rl.Rectangle{Width: float32(frame.Width), Height: float32(frame.Height)}
+	rl.ImageDraw(img, frame, src, c.code, rl.White)
+	return img
+}
+
+func (c *chrome) close() {
+	rl.UnloadImage(c.background)
+}
+
+// imageDrawRoundedRect fills a rectangle with rounded corners, image drawing has no rounded shapes
+func imageDrawRoundedRect(img *rl.Image, rect rl.Rectangle, radius float32, color rl.Color) {
+	radius = min(radius, rect.Width/2, rect.Height/2)
+	rl.ImageDrawRectangleRec(img, rl.Rectangle{X: rect.X + radius, Y: rect.Y, Width: rect.Width - radius*2, Height: rect.Height}, color)
+	rl.ImageDrawRectangleRec(img, rl.Rectangle{X: rect.X, Y: rect.Y + radius, Width: rect.Width, Height: rect.Height - radius*2}, color)
+	for _, corner := range []rl.Vector2{
+		{X: rect.X + radius, Y: rect.Y + radius},
+		{X: rect.X + rect.Width - radius - 1, Y: rect.Y + radius},
+		{X: rect.X + radius, Y: rect.Y + rect.Height - radius - 1},
+		{X: rect.X + rect.Width - radius - 1, Y: rect.Y + rect.Height - radius - 1},
+	} {
+		rl.ImageDrawCircleV(img, corner, int32(radius), color)
+	}
+}
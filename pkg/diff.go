@@ -0,0 +1,162 @@
+package gitanimate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	DiffModeChar = "char"
+	DiffModeWord = "word"
+	DiffModeLine = "line"
+)
+
+// ComputeDiffs diffs prev against curr at the granularity of mode, line and word modes
+// only ever split edits on line and word boundaries so they read like deliberate rewrites
+func ComputeDiffs(prev, curr, mode string) ([]diffmatchpatch.Diff, error) {
+	dmp := diffmatchpatch.New()
+
+	switch mode {
+	case DiffModeChar, "":
+		diffs := dmp.DiffMain(prev, curr, false)
+		diffs = dmp.DiffCleanupSemanticLossless(diffs)
+		return dmp.DiffCleanupMerge(diffs), nil
+	case DiffModeLine:
+		chars1, chars2, lines := dmp.DiffLinesToChars(prev, curr)
+		diffs := dmp.DiffMain(chars1, chars2, false)
+		return mergeDiffs(dmp.DiffCharsToLines(diffs, lines)), nil
+	case DiffModeWord:
+		runes1, runes2, words := diffWordsToRunes(prev, curr)
+		diffs := dmp.DiffMainRunes(runes1, runes2, false)
+		return mergeDiffs(diffRunesToWords(diffs, words)), nil
+	}
+
+	return nil, fmt.Errorf("unknown diff mode %q, expected one of %s, %s or %s", mode, DiffModeLine, DiffModeWord, DiffModeChar)
+}
+
+// diffWordsToRunes maps every word, whitespace run and punctuation character to a single rune,
+// the same trick DiffLinesToChars uses for lines
+func diffWordsToRunes(text1, text2 string) ([]rune, []rune, []string) {
+	words := []string{}
+	wordHash := map[string]rune{}
+
+	munge := func(text string) []rune {
+		runes := []rune{}
+		for _, word := range splitWords(text) {
+			r, ok := wordHash[word]
+			if !ok {
+				r = indexToRune(len(words))
+				words = append(words, word)
+				wordHash[word] = r
+			}
+			runes = append(runes, r)
+		}
+		return runes
+	}
+
+	return munge(text1), munge(text2), words
+}
+
+func diffRunesToWords(diffs []diffmatchpatch.Diff, words []string) []diffmatchpatch.Diff {
+	hydrated := make([]diffmatchpatch.Diff, 0, len(diffs))
+	for _, d := range diffs {
+		var text strings.Builder
+		for _, r := range d.Text {
+			text.WriteString(words[runeToIndex(r)])
+		}
+		d.Text = text.String()
+		hydrated = append(hydrated, d)
+	}
+	return hydrated
+}
+
+// indexToRune skips the surrogate range, which can't survive a round trip through a string
+func indexToRune(i int) rune {
+	r := rune(i)
+	if r >= 0xD800 {
+		r += 0x800
+	}
+	return r
+}
+
+func runeToIndex(r rune) int {
+	if r >= 0xE000 {
+		r -= 0x800
+	}
+	return int(r)
+}
+
+func splitWords(text string) []string {
+	words := []string{}
+	start := 0
+	class := -1
+	for i, r := range text {
+		c := wordClass(r)
+		//punctuation never joins with its neighbours
+		if i > start && (c != class || c == 2) {
+			words = append(words, text[start:i])
+			start = i
+		}
+		class = c
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+func wordClass(r rune) int {
+	switch {
+	case r == '\n':
+		return 2
+	case unicode.IsSpace(r):
+		return 0
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return 1
+	}
+	return 2
+}
+
+// mergeDiffs joins adjacent ops of the same type without DiffCleanupMerge's
+// character-level sliding, which would pull edits off their line/word boundaries
+func mergeDiffs(diffs []diffmatchpatch.Diff) []diffmatchpatch.Diff {
+	merged := []diffmatchpatch.Diff{}
+	var deletes, inserts strings.Builder
+
+	flush := func() {
+		if deletes.Len() > 0 {
+			merged = append(merged, diffmatchpatch.Diff{Type: diffmatchpatch.DiffDelete, Text: deletes.String()})
+			deletes.Reset()
+		}
+		if inserts.Len() > 0 {
+			merged = append(merged, diffmatchpatch.Diff{Type: diffmatchpatch.DiffInsert, Text: inserts.String()})
+			inserts.Reset()
+		}
+	}
+
+	for _, d := range diffs {
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			deletes.WriteString(d.Text)
+		case diffmatchpatch.DiffInsert:
+			inserts.WriteString(d.Text)
+		case diffmatchpatch.DiffEqual:
+			//an empty equal doesn't split a run of edits
+			if d.Text == "" {
+				continue
+			}
+			flush()
+			if len(merged) > 0 && merged[len(merged)-1].Type == diffmatchpatch.DiffEqual {
+				merged[len(merged)-1].Text += d.Text
+				continue
+			}
+			merged = append(merged, d)
+		}
+	}
+	flush()
+
+	return merged
+}
@@ -0,0 +1,149 @@
+package gitanimate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffSides rebuilds the before and after text from a diff
+func diffSides(diffs []diffmatchpatch.Diff) (string, string) {
+	var prev, curr strings.Builder
+	for _, d := range diffs {
+		if d.Type != diffmatchpatch.DiffInsert {
+			prev.WriteString(d.Text)
+		}
+		if d.Type != diffmatchpatch.DiffDelete {
+			curr.WriteString(d.Text)
+		}
+	}
+	return prev.String(), curr.String()
+}
+
+func TestComputeDiffs(t *testing.T) {
+	tests := []struct {
+		name       string
+		prev, curr string
+	}{
+		{"empty to text", "", "func main() {}\n"},
+		{"text to empty", "func main() {}\n", ""},
+		{"renamed identifier", "x := count + 1\nreturn count\n", "x := total + 1\nreturn total\n"},
+		{"added lines", "a\nb\n", "a\nnew line\nb\nend\n"},
+		{"no trailing newline", "a\nb", "a\nc"},
+		{"unicode", "naïve := \"日本\"\n", "naive := \"日本語\"\n"},
+	}
+
+	for _, mode := range []string{DiffModeChar, DiffModeWord, DiffModeLine} {
+		for _, tt := range tests {
+			diffs, err := ComputeDiffs(tt.prev, tt.curr, mode)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", mode, tt.name, err)
+			}
+			prev, curr := diffSides(diffs)
+			if prev != tt.prev || curr != tt.curr {
+				t.Errorf("%s/%s: rebuilt %q -> %q, want %q -> %q", mode, tt.name, prev, curr, tt.prev, tt.curr)
+			}
+		}
+	}
+
+	if _, err := ComputeDiffs("a", "b", "sentence"); err == nil {
+		t.Error("expected an error for an unknown diff mode")
+	}
+}
+
+func TestComputeDiffsBoundaries(t *testing.T) {
+	prev := "x := count + 1\nkeep\n"
+	curr := "x := total + 1\nkeep\n"
+
+	tests := []struct {
+		mode    string
+		changed []string
+	}{
+		//whole words and whole lines, never the shared "t" of count and total
+		{DiffModeWord, []string{"count", "total"}},
+		{DiffModeLine, []string{"x := count + 1\n", "x := total + 1\n"}},
+	}
+	for _, tt := range tests {
+		diffs, err := ComputeDiffs(prev, curr, tt.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed := []string{}
+		for _, d := range diffs {
+			if d.Type != diffmatchpatch.DiffEqual {
+				changed = append(changed, d.Text)
+			}
+		}
+		if strings.Join(changed, "|") != strings.Join(tt.changed, "|") {
+			t.Errorf("%s: changed %q, want %q", tt.mode, changed, tt.changed)
+		}
+	}
+}
+
+func TestWordRunesSkipSurrogates(t *testing.T) {
+	for _, i := range []int{0, 1, 0xD7FF, 0xD800, 0xDFFF, 0xE000, 0x10000} {
+		r := indexToRune(i)
+		if r >= 0xD800 && r <= 0xDFFF {
+			t.Errorf("indexToRune(%#x) = %#x, a surrogate", i, r)
+		}
+		if got := runeToIndex(r); got != i {
+			t.Errorf("runeToIndex(indexToRune(%#x)) = %#x", i, got)
+		}
+		if []rune(string(r))[0] != r {
+			t.Errorf("rune %#x for index %#x doesn't survive a string", r, i)
+		}
+	}
+
+	//enough distinct words to run past the surrogate range
+	var text strings.Builder
+	for i := 0; i < 0xE000; i++ {
+		fmt.Fprintf(&text, "w%d ", i)
+	}
+	runes, _, words := diffWordsToRunes(text.String(), "")
+	for _, r := range runes {
+		if r >= 0xD800 && r <= 0xDFFF {
+			t.Fatalf("word mapped to surrogate %#x", r)
+		}
+	}
+	diffs := diffRunesToWords([]diffmatchpatch.Diff{{Type: diffmatchpatch.DiffEqual, Text: string(runes)}}, words)
+	if diffs[0].Text != text.String() {
+		t.Error("words past the surrogate range don't survive the round trip")
+	}
+}
+
+func TestMergeDiffs(t *testing.T) {
+	del := func(s string) diffmatchpatch.Diff {
+		return diffmatchpatch.Diff{Type: diffmatchpatch.DiffDelete, Text: s}
+	}
+	ins := func(s string) diffmatchpatch.Diff {
+		return diffmatchpatch.Diff{Type: diffmatchpatch.DiffInsert, Text: s}
+	}
+	eq := func(s string) diffmatchpatch.Diff {
+		return diffmatchpatch.Diff{Type: diffmatchpatch.DiffEqual, Text: s}
+	}
+
+	tests := []struct {
+		name       string
+		diffs, out []diffmatchpatch.Diff
+	}{
+		{"joins runs", []diffmatchpatch.Diff{del("a"), ins("b"), del("c"), ins("d")}, []diffmatchpatch.Diff{del("ac"), ins("bd")}},
+		{"joins equals", []diffmatchpatch.Diff{eq("a"), eq("b"), ins("c")}, []diffmatchpatch.Diff{eq("ab"), ins("c")}},
+		{"drops empty equals", []diffmatchpatch.Diff{del("a"), eq(""), del("b")}, []diffmatchpatch.Diff{del("ab")}},
+		{"deletes before inserts", []diffmatchpatch.Diff{ins("x"), del("y"), eq("z")}, []diffmatchpatch.Diff{del("y"), ins("x"), eq("z")}},
+	}
+	for _, tt := range tests {
+		got := mergeDiffs(tt.diffs)
+		if len(got) != len(tt.out) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.out)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.out[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.out)
+				break
+			}
+		}
+	}
+}
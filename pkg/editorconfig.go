@@ -0,0 +1,256 @@
+package gitanimate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type editorConfigSection struct {
+	pattern *regexp.Regexp
+	props   map[string]string
+}
+
+// EditorConfig finds the .editorconfig files that apply to a file in a repository, from the
+// file's directory up to the repository root or the first with root = true, nearer files win
+type EditorConfig struct {
+	dir   string
+	files map[string]*editorConfigFile //by directory relative to dir, nil if there's no .editorconfig
+}
+
+// editorConfigFile is one .editorconfig, later sections win
+type editorConfigFile struct {
+	root     bool
+	sections []editorConfigSection
+}
+
+// LoadEditorConfig reads dir/.editorconfig, the ones in subdirectories are read as files in them are looked up
+func LoadEditorConfig(dir string) (*EditorConfig, error) {
+	cfg := &EditorConfig{dir: dir, files: map[string]*editorConfigFile{}}
+	if _, err := cfg.file("."); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// file reads and caches the .editorconfig in rel, a directory relative to the repository root
+func (c *EditorConfig) file(rel string) (*editorConfigFile, error) {
+	if f, ok := c.files[rel]; ok {
+		return f, nil
+	}
+	//cached before reading so a broken file is only reported once
+	c.files[rel] = nil
+	f, err := readEditorConfig(filepath.Join(c.dir, filepath.FromSlash(rel), ".editorconfig"), path.Join(rel, ".editorconfig"))
+	if err != nil {
+		return nil, err
+	}
+	c.files[rel] = f
+	return f, nil
+}
+
+// readEditorConfig parses the .editorconfig at file, returning nil if there isn't one, name is used in errors
+func readEditorConfig(file, name string) (*editorConfigFile, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", name, err)
+	}
+	defer f.Close()
+
+	cfg := &editorConfigFile{}
+	var section *editorConfigSection
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			pattern, err := editorConfigPattern(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", name, lineNo, err)
+			}
+			cfg.sections = append(cfg.sections, editorConfigSection{pattern: pattern, props: map[string]string{}})
+			section = &cfg.sections[len(cfg.sections)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", name, lineNo)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.ToLower(strings.TrimSpace(value))
+		if section != nil {
+			section.props[key] = value
+		} else if key == "root" {
+			//root is the only property allowed before the first section
+			cfg.root = value == "true"
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+
+	return cfg, nil
+}
+
+// TabWidth is the tab_width for filename (relative to the repository root),
+// falling back to a numeric indent_size, 0 if neither is set
+func (c *EditorConfig) TabWidth(filename string) int {
+	if c == nil {
+		return 0
+	}
+
+	filename = path.Clean(filepath.ToSlash(filename))
+	//files outside the repository only get the root .editorconfig
+	dirs := []string{"."}
+	if !path.IsAbs(filename) && filename != ".." && !strings.HasPrefix(filename, "../") {
+		dirs = nil
+		for d := path.Dir(filename); ; d = path.Dir(d) {
+			dirs = append(dirs, d)
+			if d == "." {
+				break
+			}
+		}
+	}
+
+	//nearest first, stopping at root = true
+	type found struct {
+		dir string
+		f   *editorConfigFile
+	}
+	files := []found{}
+	for _, d := range dirs {
+		f, err := c.file(d)
+		if err != nil {
+			Logger.Errorf("Ignoring %s: %v", path.Join(d, ".editorconfig"), err)
+			continue
+		}
+		if f == nil {
+			continue
+		}
+		files = append(files, found{d, f})
+		if f.root {
+			break
+		}
+	}
+
+	props := map[string]string{}
+	for i := len(files) - 1; i >= 0; i-- {
+		//a file's globs are relative to its own directory
+		name := filename
+		if files[i].dir != "." {
+			name = strings.TrimPrefix(filename, files[i].dir+"/")
+		}
+		for _, s := range files[i].f.sections {
+			if s.pattern.MatchString(name) {
+				for k, v := range s.props {
+					props[k] = v
+				}
+			}
+		}
+	}
+
+	for _, key := range []string{"tab_width", "indent_size"} {
+		if n, err := strconv.Atoi(props[key]); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// editorConfigPattern turns a section glob into a regexp, globs without a slash match in any directory
+func editorConfigPattern(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(glob, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	glob = strings.TrimPrefix(glob, "/")
+
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		case '{':
+			end := strings.IndexByte(glob[i:], '}')
+			//an unterminated brace is literal
+			if end < 0 {
+				b.WriteString(`\{`)
+				continue
+			}
+			//{1..3} numeric ranges
+			if lo, hi, ok := strings.Cut(glob[i+1:i+end], ".."); ok {
+				loN, errLo := strconv.Atoi(lo)
+				hiN, errHi := strconv.Atoi(hi)
+				if errLo == nil && errHi == nil {
+					nums := []string{}
+					for n := min(loN, hiN); n <= max(loN, hiN); n++ {
+						nums = append(nums, strconv.Itoa(n))
+					}
+					b.WriteString("(?:" + strings.Join(nums, "|") + ")")
+					i += end
+					continue
+				}
+			}
+			braces++
+			b.WriteString("(?:")
+		case '}':
+			if braces > 0 {
+				braces--
+				b.WriteString(")")
+			} else {
+				b.WriteString(`\}`)
+			}
+		case ',':
+			if braces > 0 {
+				b.WriteString("|")
+			} else {
+				b.WriteString(",")
+			}
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(strings.Repeat(")", braces))
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
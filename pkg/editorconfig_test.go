@@ -0,0 +1,181 @@
+package gitanimate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorConfigPattern(t *testing.T) {
+	tests := []struct {
+		glob        string
+		match, miss []string
+	}{
+		{"*.go", []string{"a.go", "pkg/a.go", "a/b/c.go"}, []string{"a.gox", "a.py"}},
+		{"pkg/*.go", []string{"pkg/a.go"}, []string{"a.go", "pkg/sub/a.go", "x/pkg/a.go"}},
+		{"/pkg/*.go", []string{"pkg/a.go"}, []string{"pkg/sub/a.go"}},
+		{"src/**.go", []string{"src/a.go", "src/a/b/c.go"}, []string{"a.go", "lib/src.go"}},
+		{"**/test_*", []string{"a/test_x", "a/b/test_y"}, []string{"a/b/x_test"}},
+		{"?.c", []string{"a.c", "d/b.c"}, []string{"ab.c", ".c"}},
+		{"[!x].c", []string{"a.c"}, []string{"x.c"}},
+		{"[ab].c", []string{"a.c", "b.c"}, []string{"c.c"}},
+		{"*.{js,ts}", []string{"a.js", "a.ts"}, []string{"a.py", "a.{js,ts}"}},
+		{"{Makefile,*.mk}", []string{"Makefile", "rules/x.mk"}, []string{"makefile"}},
+		{"file{1..10}.txt", []string{"file1.txt", "file5.txt", "file10.txt"}, []string{"file0.txt", "file11.txt"}},
+		{"v{3..1}", []string{"v1", "v2", "v3"}, []string{"v4"}},
+		{"{a,b", []string{"{a,b"}, []string{"a", "b"}},
+		{"a}", []string{"a}"}, []string{"a"}},
+		{`\*.md`, []string{"*.md"}, []string{"a.md"}},
+		{"a+b.(x)", []string{"a+b.(x)"}, []string{"aab.x"}},
+	}
+	for _, tt := range tests {
+		re, err := editorConfigPattern(tt.glob)
+		if err != nil {
+			t.Errorf("%s: %v", tt.glob, err)
+			continue
+		}
+		for _, name := range tt.match {
+			if !re.MatchString(name) {
+				t.Errorf("%s: should match %q (%s)", tt.glob, name, re)
+			}
+		}
+		for _, name := range tt.miss {
+			if re.MatchString(name) {
+				t.Errorf("%s: shouldn't match %q (%s)", tt.glob, name, re)
+			}
+		}
+	}
+}
+
+func writeEditorConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".editorconfig"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEditorConfigTabWidth(t *testing.T) {
+	dir := t.TempDir()
+	writeEditorConfig(t, dir, `root = true
+
+[*]
+indent_size = 2
+
+[*.go]
+tab_width = 8
+
+[Makefile]
+indent_size = tab
+tab_width = 4
+
+[*.py]
+indent_size = tab
+`)
+	writeEditorConfig(t, filepath.Join(dir, "web"), `
+[*.go]
+tab_width = 3
+
+[lib/*.js]
+indent_size = 6
+`)
+	writeEditorConfig(t, filepath.Join(dir, "vendor"), `root = true
+
+[*.c]
+tab_width = 5
+`)
+
+	cfg, err := LoadEditorConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file string
+		want int
+	}{
+		{"main.go", 8},
+		{"pkg/render.go", 8},
+		{"README.md", 2},
+		{"Makefile", 4},
+		{"x.py", 0},
+		{"web/main.go", 3},
+		{"web/index.js", 2},
+		{"web/lib/a.js", 6},
+		{"web/other/lib/a.js", 2},
+		{"vendor/x.c", 5},
+		{"vendor/x.go", 0},
+		{"../outside.go", 8},
+	}
+	for _, tt := range tests {
+		if got := cfg.TabWidth(tt.file); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.file, got, tt.want)
+		}
+	}
+
+	var none *EditorConfig
+	if got := none.TabWidth("main.go"); got != 0 {
+		t.Errorf("nil config: got %d, want 0", got)
+	}
+}
+
+func TestLoadEditorConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadEditorConfig(dir)
+	if err != nil {
+		t.Fatalf("missing .editorconfig: %v", err)
+	}
+	if got := cfg.TabWidth("a.go"); got != 0 {
+		t.Errorf("missing .editorconfig: got %d, want 0", got)
+	}
+
+	writeEditorConfig(t, dir, "[*]\nindent_size\n")
+	if _, err := LoadEditorConfig(dir); err == nil {
+		t.Errorf("line without =: expected an error")
+	}
+
+	//a broken nested file is skipped
+	writeEditorConfig(t, dir, "[*]\ntab_width = 4\n")
+	writeEditorConfig(t, filepath.Join(dir, "sub"), "[*]\ntab_width\n")
+	cfg, err = LoadEditorConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.TabWidth("sub/a.go"); got != 4 {
+		t.Errorf("broken nested .editorconfig: got %d, want 4", got)
+	}
+}
+
+func TestApplyTabWidth(t *testing.T) {
+	dir := t.TempDir()
+	writeEditorConfig(t, dir, "[*.go]\ntab_width = 8\n")
+	cfg, err := LoadEditorConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(w int) { tabWidth = w }(tabWidth)
+
+	tests := []struct {
+		name string
+		file string
+		flag int
+		cfg  *EditorConfig
+		want int
+	}{
+		{"default", "a.go", 0, nil, DefaultTabWidth},
+		{"editorconfig", "a.go", 0, cfg, 8},
+		{"editorconfig without a match", "a.py", 0, cfg, DefaultTabWidth},
+		{"flag", "a.py", 2, nil, 2},
+		{"flag over editorconfig", "a.go", 2, cfg, 2},
+	}
+	for _, tt := range tests {
+		applyTabWidth(&AnimateDiffParams{
+			Filename: tt.file,
+			Params:   &AnimateParams{TabWidth: tt.flag, EditorConfig: tt.cfg},
+		})
+		if tabWidth != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, tabWidth, tt.want)
+		}
+	}
+}
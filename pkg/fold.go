@@ -0,0 +1,119 @@
+package gitanimate
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// folding fewer lines than this saves too little to be worth the marker
+const foldMinLines = 3
+
+// fold hides the Lines whole lines of the rendered text between Start and End behind a single marker row
+type fold struct {
+	Start int
+	End   int
+	Lines int
+}
+
+// foldEqual folds the lines of an unchanged run, rendered at pos, that are more than
+// context lines away from the edits either side of it
+func foldEqual(text string, pos int, lineStart bool, context int, editBefore, editAfter bool) *fold {
+	starts := lineStarts(text)
+	newlines := len(starts) - 1
+
+	//only whole lines fold, skip the tail of a line the previous edit is on
+	first := 0
+	if !lineStart {
+		first = 1
+	}
+	if editBefore {
+		first += context
+	}
+
+	last := newlines
+	if editAfter {
+		last -= context
+	}
+
+	if last-first < foldMinLines {
+		return nil
+	}
+	return &fold{Start: pos + starts[first], End: pos + starts[last], Lines: last - first}
+}
+
+// foldDiffs folds the text shown before any of diffs have been animated
+func foldDiffs(diffs []diffmatchpatch.Diff, context int) []fold {
+	folds := []fold{}
+	if context <= 0 || !hasEdits(diffs) {
+		return folds
+	}
+
+	str := ""
+	for i, d := range diffs {
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			if f := foldEqual(d.Text, len(str), len(str) == 0 || str[len(str)-1] == '\n', context, i > 0, i < len(diffs)-1); f != nil {
+				folds = append(folds, *f)
+			}
+			str += d.Text
+		case diffmatchpatch.DiffDelete:
+			str += d.Text
+		}
+	}
+	return folds
+}
+
+func hasEdits(diffs []diffmatchpatch.Diff) bool {
+	for _, d := range diffs {
+		if d.Type != diffmatchpatch.DiffEqual && d.Text != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func foldAt(folds []fold, idx int) *fold {
+	for i := range folds {
+		if idx >= folds[i].Start && idx < folds[i].End {
+			return &folds[i]
+		}
+	}
+	return nil
+}
+
+// hiddenLines is how many rows the folds before pos take out of the layout
+func hiddenLines(folds []fold, pos int) int {
+	hidden := 0
+	for _, f := range folds {
+		if f.End <= pos {
+			hidden += f.Lines - 1
+		}
+	}
+	return hidden
+}
+
+// renderFoldMarker draws a "⋯ 120 lines ⋯" row, the ellipses are drawn rather than typeset
+// so they don't depend on the font having the glyph
+func renderFoldMarker(lines int, x, y, width float32) {
+	color := getColorForTokenType(chroma.Comment)
+	rl.DrawRectangleRec(rl.Rectangle{X: x, Y: y, Width: width, Height: lineHeight}, rl.Fade(ui.Selection, 0.3))
+
+	label := fmt.Sprintf("%d lines", lines)
+	size := rl.MeasureTextEx(font, label, fontSize, 0)
+	dotGap := fontSize / 4
+	midY := y + lineHeight/2
+
+	ellipsis := func(x float32) {
+		for i := 0; i < 3; i++ {
+			rl.DrawCircleV(rl.Vector2{X: x + float32(i)*dotGap, Y: midY}, 1.5, color)
+		}
+	}
+
+	ellipsis(x + dotGap)
+	labelX := x + dotGap*5
+	rl.DrawTextEx(font, label, rl.Vector2{X: labelX, Y: y + (lineHeight-size.Y)/2}, fontSize, 0, color)
+	ellipsis(labelX + size.X + dotGap*2)
+}
@@ -0,0 +1,107 @@
+package gitanimate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// numberedLines is n three byte lines, "a0\n" to "a9\n" then repeating
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "a%d\n", i%10)
+	}
+	return b.String()
+}
+
+func TestFoldDiffs(t *testing.T) {
+	eq := func(text string) diffmatchpatch.Diff {
+		return diffmatchpatch.Diff{Type: diffmatchpatch.DiffEqual, Text: text}
+	}
+	ins := func(text string) diffmatchpatch.Diff {
+		return diffmatchpatch.Diff{Type: diffmatchpatch.DiffInsert, Text: text}
+	}
+	del := func(text string) diffmatchpatch.Diff {
+		return diffmatchpatch.Diff{Type: diffmatchpatch.DiffDelete, Text: text}
+	}
+
+	tests := []struct {
+		name    string
+		diffs   []diffmatchpatch.Diff
+		context int
+		want    []fold
+	}{
+		{"no edits", []diffmatchpatch.Diff{eq(numberedLines(20))}, 2, []fold{}},
+		{"folding off", []diffmatchpatch.Diff{eq(numberedLines(10)), ins("x\n"), eq(numberedLines(10))}, 0, []fold{}},
+		{
+			"either side of an insert",
+			[]diffmatchpatch.Diff{eq(numberedLines(10)), ins("x\n"), eq(numberedLines(10))},
+			2,
+			//the insert isn't in the text yet, so the second run starts at 30
+			[]fold{{Start: 0, End: 24, Lines: 8}, {Start: 36, End: 60, Lines: 8}},
+		},
+		{
+			"deletes stay in the text",
+			[]diffmatchpatch.Diff{eq(numberedLines(10)), del("gone\n"), eq(numberedLines(10))},
+			3,
+			[]fold{{Start: 0, End: 21, Lines: 7}, {Start: 44, End: 65, Lines: 7}},
+		},
+		{
+			"too short between edits",
+			[]diffmatchpatch.Diff{ins("x\n"), eq(numberedLines(6)), ins("y\n")},
+			2,
+			[]fold{},
+		},
+		{
+			"just long enough between edits",
+			[]diffmatchpatch.Diff{ins("x\n"), eq(numberedLines(7)), ins("y\n")},
+			2,
+			[]fold{{Start: 6, End: 15, Lines: 3}},
+		},
+		{
+			"mid line edit skips the rest of its line",
+			[]diffmatchpatch.Diff{eq("ab"), del("x"), eq("c\n" + numberedLines(8))},
+			2,
+			[]fold{{Start: 11, End: 29, Lines: 6}},
+		},
+	}
+	for _, tt := range tests {
+		if got := foldDiffs(tt.diffs, tt.context); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFoldLookups(t *testing.T) {
+	folds := []fold{{Start: 0, End: 24, Lines: 8}, {Start: 36, End: 60, Lines: 8}}
+
+	tests := []struct {
+		pos    int
+		at     int //index into folds, -1 for none
+		hidden int
+	}{
+		{0, 0, 0},
+		{23, 0, 0},
+		{24, -1, 7},
+		{30, -1, 7},
+		{36, 1, 7},
+		{59, 1, 7},
+		{60, -1, 14},
+	}
+	for _, tt := range tests {
+		var want *fold
+		if tt.at >= 0 {
+			want = &folds[tt.at]
+		}
+		if got := foldAt(folds, tt.pos); got != want {
+			t.Errorf("foldAt %d: got %+v, want %+v", tt.pos, got, want)
+		}
+		if got := hiddenLines(folds, tt.pos); got != tt.hidden {
+			t.Errorf("hiddenLines %d: got %d, want %d", tt.pos, got, tt.hidden)
+		}
+	}
+}
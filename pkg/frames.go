@@ -0,0 +1,236 @@
+package gitanimate
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var (
+	//frames are drawn into target at renderScale times the canvas, the size of the output without its chrome
+	target       rl.RenderTexture2D
+	renderScale  float32 = 1
+	canvasWidth  float32
+	canvasHeight float32
+)
+
+// frameRecorder captures every drawn frame to a temporary directory and encodes them once the clip is done
+type frameRecorder struct {
+	temp       string
+	frameCount int
+	//frames of terminal scenes, which don't count towards MaxFrameCount
+	sceneFrames int
+	wg          sync.WaitGroup
+	chrome      *chrome
+	//subtitle format to write next to the video, empty for none
+	subtitles string
+	cues      []subtitleCue
+	openCues  map[string]int
+}
+
+func newFrameRecorder() (*frameRecorder, error) {
+	temp, err := os.MkdirTemp("", "gitanimate")
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+
+	go func() {
+		<-c
+		Logger.Info("Cleaning up temporary files")
+		err := os.RemoveAll(temp)
+		if err != nil {
+			Logger.Errorf("Failed to clean up temporary files: %v", err)
+		}
+
+		os.Exit(1)
+	}()
+
+	return &frameRecorder{temp: temp}, nil
+}
+
+// openWindow sets up the window, theme and fonts, text is everything the clip will draw so its glyphs get loaded
+func openWindow(params *AnimateParams, show bool, text string) rl.Color {
+	rl.SetTraceLogLevel(rl.LogError)
+	//no HighDPI, frames come from the render target so the window's size doesn't matter
+	var flags uint32 = rl.FlagVsyncHint
+	if !show {
+		flags |= rl.FlagWindowHidden
+	}
+	rl.SetConfigFlags(flags)
+	//libx264 needs even dimensions
+	params.Width += params.Width % 2
+	params.Height += params.Height % 2
+	//with chrome the canvas only holds the code, the frame goes around it as frames are captured
+	_, code := chromeLayout(params)
+	canvasWidth, canvasHeight = code.Width, code.Height
+	rl.InitWindow(int32(canvasWidth), int32(canvasHeight), "gitanimate")
+
+	rl.SetTargetFPS(FrameRate)
+
+	renderScale = 1
+	if params.Scale > 0 {
+		renderScale = params.Scale
+	}
+	target = rl.LoadRenderTexture(int32(math.Round(float64(canvasWidth*renderScale))), int32(math.Round(float64(canvasHeight*renderScale))))
+	rl.SetTextureFilter(target.Texture, rl.FilterBilinear)
+
+	applyTheme(params)
+	bg := style.Get(chroma.Background)
+	bgRl := rl.Color{R: bg.Background.Red(), G: bg.Background.Green(), B: bg.Background.Blue(), A: 255}
+
+	loadFonts(params.Font, params.FontFallback, text)
+	loadFontVariants(params.FontBold, params.FontItalic, params.FontBoldItalic, text)
+	applyTextParams(params)
+
+	return bgRl
+}
+
+func closeWindow() {
+	unloadFonts()
+	rl.UnloadRenderTexture(target)
+	rl.CloseWindow()
+}
+
+// beginFrame starts drawing a frame into the render target, in canvas coordinates whatever the scale
+func beginFrame(bg rl.Color) {
+	rl.BeginTextureMode(target)
+	rl.ClearBackground(bg)
+	rl.BeginMode2D(rl.Camera2D{Zoom: renderScale})
+}
+
+// endFrame finishes drawing into the render target and shows it in the window
+func endFrame() {
+	rl.EndMode2D()
+	rl.EndTextureMode()
+
+	rl.BeginDrawing()
+	//render textures are stored upside down
+	src := rl.Rectangle{Width: float32(target.Texture.Width), Height: -float32(target.Texture.Height)}
+	dst := rl.Rectangle{Width: float32(rl.GetScreenWidth()), Height: float32(rl.GetScreenHeight())}
+	rl.DrawTexturePro(target.Texture, src, dst, rl.Vector2{}, 0, rl.White)
+	rl.EndDrawing()
+}
+
+// clipFrames is how many frames of the clip itself have been captured
+func (f *frameRecorder) clipFrames() int {
+	return f.frameCount - f.sceneFrames
+}
+
+// beginScissor clips drawing to a rectangle in canvas coordinates, raylib's scissor is in the
+// render target's pixels and ignores the camera
+func beginScissor(x, y, width, height float32) {
+	rl.BeginScissorMode(int32(x*renderScale), int32(y*renderScale),
+		int32(math.Ceil(float64(width*renderScale))), int32(math.Ceil(float64(height*renderScale))))
+}
+
+func endScissor() {
+	rl.EndScissorMode()
+}
+
+// beginCamera draws through cam until endCamera, on top of the render scale
+func beginCamera(cam rl.Camera2D) {
+	rl.EndMode2D()
+	cam.Offset = rl.Vector2Scale(cam.Offset, renderScale)
+	cam.Zoom *= renderScale
+	rl.BeginMode2D(cam)
+}
+
+func endCamera() {
+	rl.EndMode2D()
+	rl.BeginMode2D(rl.Camera2D{Zoom: renderScale})
+}
+
+// capture grabs the frame just drawn, call after endFrame
+func (f *frameRecorder) capture() {
+	img := rl.LoadImageFromTexture(target.Texture)
+	if img == nil {
+		Logger.Fatal("Failed to load image from render target")
+	}
+	rl.ImageFlipVertical(img)
+	width, height := int32(canvasWidth), int32(canvasHeight)
+
+	frame := f.frameCount
+	f.frameCount++
+
+	f.wg.Add(1)
+	go func() {
+		//supersampled frames are scaled down to the output size, the chrome does that as it composites
+		if f.chrome != nil {
+			framed := f.chrome.composite(img)
+			rl.UnloadImage(img)
+			img = framed
+		} else if img.Width != width || img.Height != height {
+			rl.ImageResize(img, width, height)
+		}
+
+		imgPath := filepath.Join(f.temp, fmt.Sprintf(FrameFormat, frame))
+		if !rl.ExportImage(*img, imgPath) {
+			rl.UnloadImage(img)
+			Logger.Fatalf("Failed to export frame %s", imgPath)
+		}
+		rl.UnloadImage(img)
+
+		f.wg.Done()
+	}()
+}
+
+func (f *frameRecorder) encode(output string) error {
+	f.wg.Wait()
+	if err := encodeFramesToVideo(f.temp, f.frameCount, output); err != nil {
+		return err
+	}
+	if f.subtitles == "" || len(f.cues) == 0 {
+		return nil
+	}
+	return writeSubtitles(strings.TrimSuffix(output, filepath.Ext(output))+"."+f.subtitles, f.subtitles, f.cues)
+}
+
+func (f *frameRecorder) close() {
+	f.wg.Wait()
+	if f.chrome != nil {
+		f.chrome.close()
+	}
+	os.RemoveAll(f.temp)
+}
+
+func encodeFramesToVideo(temp string, frameCount int, output string) error {
+	if frameCount == 0 {
+		return fmt.Errorf("no frames captured to encode")
+	}
+
+	inputPattern := filepath.Join(temp, FrameFormat)
+
+	cmd := exec.Command(
+		FFmpegPath,
+		"-y",
+		"-framerate", fmt.Sprintf("%d", FrameRate),
+		"-i", inputPattern,
+		"-c:v", "libx264",
+		"-preset", FFmpegPreset,
+		"-crf", "18",
+		"-pix_fmt", "yuv420p",
+		output,
+	)
+
+	//cmd.Stdout = os.Stdout
+	//cmd.Stderr = os.Stderr
+
+	//Logger.Debug("Starting FFmpeg encoding...")
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("FFmpeg error: %v", err)
+	}
+
+	return nil
+}
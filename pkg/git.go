@@ -13,12 +13,8 @@ type CommitFile struct {
 	FileName       string
 	CurrentContent string
 	PrevContent    string
-}
-
-type GitWrap interface {
-	GetFiles() ([]*CommitFile, error)
-	PopCommit() (string, error)
-	CurrCommit() string
+	//optional scripted animation, played instead of the diff between the contents
+	Segments []*Segment
 }
 
 type GitWrapper struct {
@@ -81,6 +77,33 @@ func NewGitWrapper(repoPath, start, end string) (*GitWrapper, error) {
 	}, nil
 }
 
+// Next yields the current commit as a changeset and moves on to the following one
+func (g *GitWrapper) Next() (*Changeset, error) {
+	if g.Idx >= len(g.Commits) {
+		return nil, io.EOF
+	}
+
+	files, err := g.GetFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get files from commit %s: %v", g.CurrCommit(), err)
+	}
+
+	commit := g.Commits[g.Idx]
+	g.Idx++
+
+	return &Changeset{
+		ID:      commit.Hash.String(),
+		Message: commit.Message,
+		Author:  commit.Author.Name,
+		Time:    commit.Author.When,
+		Files:   files,
+	}, nil
+}
+
+func (g *GitWrapper) Len() int {
+	return len(g.Commits)
+}
+
 func (g *GitWrapper) PopCommit() (string, error) {
 	if g.Idx+1 >= len(g.Commits) {
 		return "", fmt.Errorf("no more Commits to pop")
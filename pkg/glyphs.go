@@ -0,0 +1,299 @@
+package gitanimate
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/image/font/sfnt"
+)
+
+const (
+	defaultFontPath = "assets/fonts/IBMPlexMono-Regular.ttf"
+	fontLoadSize    = 120
+)
+
+const (
+	variantRegular    = 0
+	variantBold       = 1
+	variantItalic     = 2
+	variantBoldItalic = variantBold | variantItalic
+)
+
+// fontFace is one font of the fallback chain, cmap tells which runes it has glyphs for
+type fontFace struct {
+	Font rl.Font
+	name string
+	data []byte
+	ext  string
+	//nil when sfnt can't read the font (say a .fnt or bitmap font), raylib may still load it
+	cmap *sfnt.Font
+	buf  sfnt.Buffer
+}
+
+var (
+	fontChain []*fontFace
+	//bold, italic and bold italic faces indexed by variant, nil when not given
+	fontVariants [variantBoldItalic + 1]*fontFace
+	//index into fontChain of the font each loaded rune is drawn with
+	glyphOwner = map[rune]int{}
+	//fixed widths, ambiguous runes are narrow regardless of the locale
+	widths         = &runewidth.Condition{}
+	tabWidth       = DefaultTabWidth
+	showWhitespace bool
+)
+
+func readFontFace(name string) (*fontFace, error) {
+	var data []byte
+	var err error
+	ext := filepath.Ext(name)
+	if name == "default" {
+		data, err = fonts.ReadFile(defaultFontPath)
+		ext = filepath.Ext(defaultFontPath)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font %s: %v", name, err)
+	}
+
+	//sfnt is only used to probe coverage, the font is still raylib's to load
+	cmap, err := sfnt.Parse(data)
+	if err != nil {
+		cmap = nil
+	}
+
+	return &fontFace{name: name, data: data, ext: ext, cmap: cmap}, nil
+}
+
+// covers is whether the font has a glyph for r, a font sfnt can't read is taken to have them all
+func (f *fontFace) covers(r rune) bool {
+	if f.cmap == nil {
+		return true
+	}
+	idx, err := f.cmap.GlyphIndex(&f.buf, r)
+	return err == nil && idx != 0
+}
+
+// load loads the font with glyphs for runes, files go through raylib's own loader like they always have
+func (f *fontFace) load(runes []rune) bool {
+	if f.name == "default" {
+		f.Font = rl.LoadFontFromMemory(f.ext, f.data, fontLoadSize, runes)
+	} else {
+		f.Font = rl.LoadFontEx(f.name, fontLoadSize, runes)
+	}
+	//raylib hands back its default font when it can't load one
+	return f.Font.Texture.ID != rl.GetFontDefault().Texture.ID
+}
+
+// unloadFonts frees the fonts loaded for a clip, call before closing the window
+func unloadFonts() {
+	faces := append(slices.Clone(fontChain), fontVariants[:]...)
+	for _, face := range faces {
+		if face != nil && face.Font.Texture.ID != 0 {
+			rl.UnloadFont(face.Font)
+		}
+	}
+	fontChain = nil
+	fontVariants = [variantBoldItalic + 1]*fontFace{}
+}
+
+// loadFonts loads name and its fallbacks with glyphs for every rune in text,
+// each rune comes from the first font in the chain that has it
+func loadFonts(name string, fallbacks []string, text string) {
+	primary, err := readFontFace(name)
+	if err != nil {
+		Logger.Errorf("Failed to load font %s, defaulting to IBM Plex Mono: %v", name, err)
+		primary, err = readFontFace("default")
+		if err != nil {
+			Logger.Errorf("Failed to load font: %s", err)
+			font = rl.GetFontDefault()
+			fontChain = nil
+			return
+		}
+		name = "default"
+	}
+
+	fontChain = []*fontFace{primary}
+	if name != "default" {
+		//the embedded font is always the last resort
+		fallbacks = append(slices.Clone(fallbacks), "default")
+	}
+	for _, fallback := range fallbacks {
+		face, err := readFontFace(fallback)
+		if err != nil {
+			Logger.Errorf("Skipping fallback font: %v", err)
+			continue
+		}
+		fontChain = append(fontChain, face)
+	}
+
+	//runes no font covers go to the primary, which draws its missing glyph box
+	assigned := make([][]rune, len(fontChain))
+	glyphOwner = map[rune]int{}
+	for _, r := range fontRunes(text) {
+		owner := 0
+		for i, face := range fontChain {
+			if face.covers(r) {
+				owner = i
+				break
+			}
+		}
+		glyphOwner[r] = owner
+		assigned[owner] = append(assigned[owner], r)
+	}
+
+	for i, face := range fontChain {
+		if i > 0 && len(assigned[i]) == 0 {
+			continue
+		}
+		if !face.load(assigned[i]) {
+			if i == 0 && name != "default" {
+				Logger.Errorf("Failed to load font %s, defaulting to IBM Plex Mono", name)
+				unloadFonts()
+				loadFonts("default", nil, text)
+				return
+			}
+			Logger.Errorf("Failed to load font %s", face.name)
+		}
+	}
+	font = primary.Font
+}
+
+// loadFontVariants loads the optional bold, italic and bold italic files, with glyphs for the runes of text they cover
+func loadFontVariants(bold, italic, boldItalic string, text string) {
+	fontVariants = [variantBoldItalic + 1]*fontFace{}
+	runes := fontRunes(text)
+	for variant, name := range map[int]string{variantBold: bold, variantItalic: italic, variantBoldItalic: boldItalic} {
+		if name == "" {
+			continue
+		}
+		face, err := readFontFace(name)
+		if err != nil {
+			Logger.Errorf("Skipping font variant: %v", err)
+			continue
+		}
+
+		covered := []rune{}
+		for _, r := range runes {
+			if face.covers(r) {
+				covered = append(covered, r)
+			}
+		}
+		if !face.load(covered) {
+			Logger.Errorf("Skipping font variant %s, it failed to load", name)
+			continue
+		}
+		fontVariants[variant] = face
+	}
+}
+
+// fontRunes is the printable ASCII range plus every other rune of text
+func fontRunes(text string) []rune {
+	set := map[rune]bool{}
+	for r := rune(0x20); r < 0x7f; r++ {
+		set[r] = true
+	}
+	for _, r := range text {
+		if r >= 0x20 && r != 0x7f {
+			set[r] = true
+		}
+	}
+
+	runes := make([]rune, 0, len(set))
+	for r := range set {
+		runes = append(runes, r)
+	}
+	slices.Sort(runes)
+	return runes
+}
+
+func glyphFont(r rune) rl.Font {
+	if owner, ok := glyphOwner[r]; ok && owner < len(fontChain) {
+		return fontChain[owner].Font
+	}
+	return font
+}
+
+// cellWidth is the advance of one column of the primary font, every rune is laid out on this grid
+func cellWidth() float32 {
+	return rl.MeasureTextEx(font, "0", fontSize, 0).X
+}
+
+// runeWidth is how much room r takes on the grid, East Asian wide runes take two cells and combining marks none
+func runeWidth(r rune) float32 {
+	if r == '\t' {
+		return float32(tabWidth) * cellWidth()
+	}
+	return float32(widths.RuneWidth(r)) * cellWidth()
+}
+
+func drawRune(r rune, x, y float32, color rl.Color) {
+	drawStyledRune(r, x, y, color, variantRegular)
+}
+
+// drawStyledRune draws r in the font for variant, bold without a bold face is drawn
+// twice a hair apart, italic without an italic face falls back to upright
+func drawStyledRune(r rune, x, y float32, color rl.Color, variant int) {
+	if r < 0x20 {
+		return
+	}
+
+	f := glyphFont(r)
+	synthetic := variant&variantBold != 0
+	for _, v := range []int{variant, variant &^ variantBold} {
+		if face := fontVariants[v]; v != variantRegular && face != nil && face.covers(r) {
+			f = face.Font
+			synthetic = variant&variantBold != 0 && v&variantBold == 0
+			break
+		}
+	}
+
+	rl.DrawTextEx(f, string(r), rl.Vector2{X: x, Y: y}, fontSize, 0, color)
+	if synthetic {
+		rl.DrawTextEx(f, string(r), rl.Vector2{X: x + max(fontSize/24, 1), Y: y}, fontSize, 0, color)
+	}
+}
+
+// tabAdvance is the width of a tab offset from the start of the line, up to the next tab stop
+func tabAdvance(offset float32) float32 {
+	cell := cellWidth()
+	col := int(math.Round(float64(offset / cell)))
+	return float32(tabWidth-col%tabWidth) * cell
+}
+
+// renderWhitespaceMarker draws a faint dot for a space or an arrow across a tab
+func renderWhitespaceMarker(r rune, x, y, width float32) {
+	color := rl.Fade(getColorForTokenType(chroma.Text), 0.25)
+	midY := y + lineHeight/2
+	if r == ' ' {
+		rl.DrawCircleV(rl.Vector2{X: x + width/2, Y: midY}, 1.5, color)
+		return
+	}
+
+	head := fontSize / 5
+	start, end := x+width*0.15, x+width*0.85
+	rl.DrawLineEx(rl.Vector2{X: start, Y: midY}, rl.Vector2{X: end, Y: midY}, 1, color)
+	rl.DrawLineEx(rl.Vector2{X: end - head, Y: midY - head}, rl.Vector2{X: end, Y: midY}, 1, color)
+	rl.DrawLineEx(rl.Vector2{X: end - head, Y: midY + head}, rl.Vector2{X: end, Y: midY}, 1, color)
+}
+
+func measureText(text string) float32 {
+	width := float32(0)
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+func drawText(text string, x, y float32, color rl.Color) {
+	for _, r := range text {
+		drawRune(r, x, y, color)
+		x += runeWidth(r)
+	}
+}
@@ -0,0 +1,63 @@
+package gitanimate
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// The animation state indexes text by byte, but only ever stops on grapheme cluster
+// boundaries so a multi-byte character, or a letter and its combining marks, is typed,
+// deleted and stepped over as one.
+
+// graphemeBounds returns the boundaries of the cluster containing i, lo <= i < hi
+func graphemeBounds(text string, i int) (int, int) {
+	if i >= len(text) {
+		return len(text), len(text)
+	}
+	i = max(i, 0)
+
+	//clusters always break after a newline, so there's no need to scan from the start
+	pos := strings.LastIndexByte(text[:i], '\n') + 1
+	state := -1
+	for {
+		cluster, _, _, newState := uniseg.FirstGraphemeClusterInString(text[pos:], state)
+		state = newState
+		if pos+len(cluster) > i {
+			return pos, pos + len(cluster)
+		}
+		pos += len(cluster)
+	}
+}
+
+// nextGrapheme is the first boundary after i
+func nextGrapheme(text string, i int) int {
+	_, hi := graphemeBounds(text, i)
+	return hi
+}
+
+// prevGrapheme is the last boundary before i
+func prevGrapheme(text string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	lo, _ := graphemeBounds(text, i-1)
+	return lo
+}
+
+// graphemeFloor rounds i down to a boundary
+func graphemeFloor(text string, i int) int {
+	lo, _ := graphemeBounds(text, i)
+	return lo
+}
+
+func runeBefore(text string, i int) rune {
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return r
+}
+
+func runeAt(text string, i int) rune {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return r
+}
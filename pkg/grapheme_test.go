@@ -0,0 +1,72 @@
+package gitanimate
+
+import "testing"
+
+// "e" + U+0301 is one cluster of two runes, 日 and é are single multi-byte runes
+const graphemeText = "a\u00e9\u65e5e\u0301\nb"
+
+func TestNextGrapheme(t *testing.T) {
+	tests := []struct {
+		i, want int
+	}{
+		{0, 1},
+		{1, 3},
+		{2, 3},
+		{3, 6},
+		{5, 6},
+		{6, 9},
+		{7, 9},
+		{9, 10},
+		{10, 11},
+		{11, 11},
+		{20, 11},
+	}
+	for _, tt := range tests {
+		if got := nextGrapheme(graphemeText, tt.i); got != tt.want {
+			t.Errorf("nextGrapheme(%q, %d) = %d, want %d", graphemeText, tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestPrevGrapheme(t *testing.T) {
+	tests := []struct {
+		i, want int
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 0},
+		{3, 1},
+		{6, 3},
+		{8, 6},
+		{9, 6},
+		{10, 9},
+		{11, 10},
+	}
+	for _, tt := range tests {
+		if got := prevGrapheme(graphemeText, tt.i); got != tt.want {
+			t.Errorf("prevGrapheme(%q, %d) = %d, want %d", graphemeText, tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestGraphemeFloor(t *testing.T) {
+	tests := []struct {
+		i, want int
+	}{
+		{-1, 0},
+		{0, 0},
+		{2, 1},
+		{4, 3},
+		{5, 3},
+		{7, 6},
+		{8, 6},
+		{9, 9},
+		{11, 11},
+		{20, 11},
+	}
+	for _, tt := range tests {
+		if got := graphemeFloor(graphemeText, tt.i); got != tt.want {
+			t.Errorf("graphemeFloor(%q, %d) = %d, want %d", graphemeText, tt.i, got, tt.want)
+		}
+	}
+}
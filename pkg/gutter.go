@@ -0,0 +1,118 @@
+package gitanimate
+
+import (
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	lineUnchanged = iota
+	lineAdded
+	lineModified
+)
+
+const gutterMarkerWidth = 3
+
+type deletion struct {
+	Pos        int
+	WholeLines bool
+}
+
+// gutterMarkers records the change state of every line of the rendered text,
+// Deleted marks lines that had whole lines removed just above them
+type gutterMarkers struct {
+	Lines   []int
+	Deleted map[int]bool
+	Summary bool
+}
+
+func computeGutter(text string, inserted []highlight, deleted []deletion) *gutterMarkers {
+	starts := lineStarts(text)
+	g := &gutterMarkers{
+		Lines:   make([]int, len(starts)),
+		Deleted: map[int]bool{},
+	}
+
+	lineEnd := func(line int) int {
+		if line+1 < len(starts) {
+			return starts[line+1]
+		}
+		return len(text)
+	}
+
+	for _, r := range inserted {
+		if r.End <= r.Start {
+			continue
+		}
+		first, _ := lineCol(starts, r.Start)
+		last, _ := lineCol(starts, r.End-1)
+		for l := first; l <= last; l++ {
+			if r.Start <= starts[l] && r.End >= lineEnd(l) {
+				if g.Lines[l] == lineUnchanged {
+					g.Lines[l] = lineAdded
+				}
+			} else {
+				g.Lines[l] = lineModified
+			}
+		}
+	}
+
+	for _, d := range deleted {
+		line, _ := lineCol(starts, d.Pos)
+		if d.WholeLines {
+			g.Deleted[line] = true
+		} else if g.Lines[line] == lineUnchanged {
+			g.Lines[line] = lineModified
+		}
+	}
+
+	return g
+}
+
+func (g *gutterMarkers) line(line int) int {
+	if g == nil || line < 0 || line >= len(g.Lines) {
+		return lineUnchanged
+	}
+	return g.Lines[line]
+}
+
+func gutterColor(state int) rl.Color {
+	switch state {
+	case lineAdded:
+		if style.Get(chroma.GenericInserted).Colour.IsSet() {
+			return getColorForTokenType(chroma.GenericInserted)
+		}
+		return rl.Green
+	case lineModified:
+		return rl.Color{R: 0x3c, G: 0x8c, B: 0xe7, A: 255}
+	}
+	return strikeColor()
+}
+
+// render draws the change bar for one visual row of a line, and the summary
+// highlight across the row when the final summary frame is shown
+func (g *gutterMarkers) render(line int, x, y, rowWidth float32, firstRow bool) {
+	if g == nil {
+		return
+	}
+
+	if state := g.line(line); state != lineUnchanged {
+		rl.DrawRectangleRec(rl.Rectangle{X: x, Y: y, Width: gutterMarkerWidth, Height: lineHeight}, gutterColor(state))
+		if g.Summary {
+			rl.DrawRectangleRec(rl.Rectangle{X: x + gutterMarkerWidth, Y: y, Width: rowWidth, Height: lineHeight}, rl.Fade(gutterColor(state), 0.15))
+		}
+	}
+
+	if firstRow && g.Deleted[line] {
+		size := lineHeight / 4
+		rl.DrawTriangle(
+			rl.Vector2{X: x, Y: y - size},
+			rl.Vector2{X: x, Y: y + size},
+			rl.Vector2{X: x + size*1.5, Y: y},
+			gutterColor(-1),
+		)
+		if g.Summary {
+			rl.DrawRectangleRec(rl.Rectangle{X: x, Y: y - 1, Width: rowWidth, Height: 2}, rl.Fade(gutterColor(-1), 0.6))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package gitanimate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestComputeGutter(t *testing.T) {
+	//lines start at 0, 2, 5 and 7, the last one empty
+	const text = "a\nbb\nc\n"
+
+	tests := []struct {
+		name     string
+		inserted []highlight
+		deleted  []deletion
+		want     []int
+		wantDel  []int
+	}{
+		{"nothing", nil, nil, []int{lineUnchanged, lineUnchanged, lineUnchanged, lineUnchanged}, nil},
+		{"whole line insert", []highlight{{Start: 2, End: 5}}, nil, []int{lineUnchanged, lineAdded, lineUnchanged, lineUnchanged}, nil},
+		{"whole lines insert", []highlight{{Start: 2, End: 7}}, nil, []int{lineUnchanged, lineAdded, lineAdded, lineUnchanged}, nil},
+		{"insert without the newline", []highlight{{Start: 2, End: 4}}, nil, []int{lineUnchanged, lineModified, lineUnchanged, lineUnchanged}, nil},
+		{"mid line insert", []highlight{{Start: 3, End: 4}}, nil, []int{lineUnchanged, lineModified, lineUnchanged, lineUnchanged}, nil},
+		{"insert across lines", []highlight{{Start: 3, End: 6}}, nil, []int{lineUnchanged, lineModified, lineModified, lineUnchanged}, nil},
+		{"empty insert", []highlight{{Start: 3, End: 3}}, nil, []int{lineUnchanged, lineUnchanged, lineUnchanged, lineUnchanged}, nil},
+		{
+			"modified isn't downgraded to added",
+			[]highlight{{Start: 3, End: 4}, {Start: 2, End: 5}},
+			nil,
+			[]int{lineUnchanged, lineModified, lineUnchanged, lineUnchanged},
+			nil,
+		},
+		{"whole line delete", nil, []deletion{{Pos: 5, WholeLines: true}}, []int{lineUnchanged, lineUnchanged, lineUnchanged, lineUnchanged}, []int{2}},
+		{"delete at the end", nil, []deletion{{Pos: 7, WholeLines: true}}, []int{lineUnchanged, lineUnchanged, lineUnchanged, lineUnchanged}, []int{3}},
+		{"mid line delete", nil, []deletion{{Pos: 1}}, []int{lineModified, lineUnchanged, lineUnchanged, lineUnchanged}, nil},
+		{
+			"mid line delete on an added line",
+			[]highlight{{Start: 2, End: 5}},
+			[]deletion{{Pos: 3}},
+			[]int{lineUnchanged, lineAdded, lineUnchanged, lineUnchanged},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		g := computeGutter(text, tt.inserted, tt.deleted)
+		if !slices.Equal(g.Lines, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, g.Lines, tt.want)
+		}
+		deleted := []int{}
+		for line := range g.Deleted {
+			deleted = append(deleted, line)
+		}
+		slices.Sort(deleted)
+		if tt.wantDel == nil {
+			tt.wantDel = []int{}
+		}
+		if !slices.Equal(deleted, tt.wantDel) {
+			t.Errorf("%s: got deleted %v, want %v", tt.name, deleted, tt.wantDel)
+		}
+	}
+}
+
+func TestGutterLine(t *testing.T) {
+	g := &gutterMarkers{Lines: []int{lineAdded, lineModified}}
+	tests := []struct {
+		g    *gutterMarkers
+		line int
+		want int
+	}{
+		{g, 0, lineAdded},
+		{g, 1, lineModified},
+		{g, -1, lineUnchanged},
+		{g, 2, lineUnchanged},
+		{nil, 0, lineUnchanged},
+	}
+	for _, tt := range tests {
+		if got := tt.g.line(tt.line); got != tt.want {
+			t.Errorf("line %d: got %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package gitanimate
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	DefaultMinimapWidth = 80
+
+	//the tallest a line gets in the minimap, longer files squeeze to fit
+	minimapRowHeight = 3
+	minimapColumns   = 100
+)
+
+// minimapWidth is the room kept on the right for the minimap, 0 without one
+var minimapWidth float32
+
+// renderMinimap draws the file in miniature down the right edge, a bar for every run of code in its token's
+// colour, with the changed lines marked and a band over the part that's on screen
+func renderMinimap(tokens []chroma.Token, scrollY, contentHeight float32, cursorIndex int, highlights []highlight, gutter *gutterMarkers) {
+	if minimapWidth <= 0 {
+		return
+	}
+
+	left := canvasWidth - minimapWidth
+	rl.DrawRectangleRec(rl.Rectangle{X: left, Y: 0, Width: minimapWidth, Height: canvasHeight}, rl.Fade(ui.LineNumber, 0.06))
+	rl.DrawRectangleRec(rl.Rectangle{X: left, Y: 0, Width: 1, Height: canvasHeight}, rl.Fade(ui.LineNumber, 0.2))
+
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString(token.Value)
+	}
+	text := b.String()
+	starts := lineStarts(text)
+
+	top := padding
+	rowHeight := min(minimapRowHeight, (canvasHeight-padding*2)/float32(len(starts)))
+	//leave a strip on the left of the map for the change markers
+	markerX := left + 2
+	codeX := markerX + gutterMarkerWidth + 2
+	charWidth := (canvasWidth - codeX - 2) / minimapColumns
+
+	rowY := func(line int) float32 {
+		return top + float32(line)*rowHeight
+	}
+
+	line, col := 0, 0
+	for _, token := range tokens {
+		color := rl.Fade(getColorForTokenType(token.Type), 0.7)
+		runStart := -1
+		flush := func() {
+			if runStart >= 0 && runStart < minimapColumns {
+				width := float32(min(col, minimapColumns)-runStart) * charWidth
+				rl.DrawRectangleRec(rl.Rectangle{X: codeX + float32(runStart)*charWidth, Y: rowY(line), Width: width, Height: max(rowHeight*0.7, 0.5)}, color)
+			}
+			runStart = -1
+		}
+
+		for _, r := range token.Value {
+			switch {
+			case r == '\n':
+				flush()
+				line++
+				col = 0
+			case r == '\t':
+				flush()
+				col += tabWidth - col%tabWidth
+			case unicode.IsSpace(r):
+				flush()
+				col++
+			default:
+				if runStart < 0 {
+					runStart = col
+				}
+				col++
+			}
+		}
+		flush()
+	}
+
+	for l := range starts {
+		if state := gutter.line(l); state != lineUnchanged {
+			rl.DrawRectangleRec(rl.Rectangle{X: markerX, Y: rowY(l), Width: gutterMarkerWidth, Height: max(rowHeight, 1)}, gutterColor(state))
+		}
+		if gutter != nil && gutter.Deleted[l] {
+			rl.DrawRectangleRec(rl.Rectangle{X: markerX, Y: rowY(l) - 0.5, Width: gutterMarkerWidth * 2, Height: 1}, strikeColor())
+		}
+	}
+
+	//selections, strikes and paste flashes cover their whole lines
+	for _, h := range highlights {
+		if h.End <= h.Start {
+			continue
+		}
+		first, _ := lineCol(starts, h.Start)
+		last, _ := lineCol(starts, h.End-1)
+		rl.DrawRectangleRec(rl.Rectangle{X: codeX, Y: rowY(first), Width: canvasWidth - codeX, Height: float32(last-first+1) * rowHeight}, rl.Fade(h.Color, 0.5))
+	}
+
+	if cursorIndex >= 0 {
+		cursorLine, _ := lineCol(starts, min(cursorIndex, len(text)))
+		rl.DrawRectangleRec(rl.Rectangle{X: codeX, Y: rowY(cursorLine), Width: canvasWidth - codeX, Height: max(rowHeight, 1)}, rl.Fade(ui.Cursor, 0.5))
+	}
+
+	//the band follows the camera through the rendered content, which folds and wrapping stretch away from the file's lines
+	if contentHeight > 0 {
+		mapHeight := float32(len(starts)) * rowHeight
+		y := top + scrollY/contentHeight*mapHeight
+		height := min(canvasHeight/contentHeight, 1) * mapHeight
+		rl.DrawRectangleRec(rl.Rectangle{X: left + 1, Y: y, Width: minimapWidth - 1, Height: height}, rl.Fade(ui.LineNumber, 0.15))
+	}
+}
@@ -0,0 +1,142 @@
+package gitanimate
+
+import (
+	"sort"
+)
+
+const (
+	//jumps further than this many pages use a "go to line" instead of paging
+	gotoPages = 3
+	//horizontal moves longer than this jump word-wise
+	maxCharSteps = 8
+	gotoDuration = 0.75
+)
+
+func (a *AnimState) navigating() bool {
+	return len(a.NavPath) > 0
+}
+
+// planNavigation lays out the key presses an editor user would make to get the cursor
+// from one edit site to the next: page jumps and arrow keys, or a go to line for long trips
+func (a *AnimState) planNavigation(from, to int) {
+	a.NavPath = nil
+	a.NavProgress = 0
+	if from == to || a.NavSpeed <= 0 {
+		return
+	}
+
+	starts := lineStarts(a.Text)
+	fromLine, fromCol := lineCol(starts, from)
+	toLine, _ := lineCol(starts, to)
+	pageLines := max(a.PageLines, 1)
+
+	if abs(toLine-fromLine) > pageLines*gotoPages {
+		a.GotoLine = toLine + 1
+		a.GotoTimer = gotoDuration
+		a.NavPath = []int{to}
+		return
+	}
+
+	path := []int{}
+	line := fromLine
+	step := 1
+	if toLine < fromLine {
+		step = -1
+	}
+	for abs(toLine-line) > pageLines {
+		line += step * pageLines
+		path = append(path, graphemeFloor(a.Text, lineIndex(a.Text, starts, line, fromCol)))
+	}
+	for line != toLine {
+		line += step
+		path = append(path, graphemeFloor(a.Text, lineIndex(a.Text, starts, line, fromCol)))
+	}
+
+	pos := graphemeFloor(a.Text, lineIndex(a.Text, starts, toLine, fromCol))
+	if abs(to-pos) > maxCharSteps {
+		path = append(path, wordStops(a.Text, pos, to)...)
+	} else {
+		for pos < to {
+			pos = min(nextGrapheme(a.Text, pos), to)
+			path = append(path, pos)
+		}
+		for pos > to {
+			pos = max(prevGrapheme(a.Text, pos), to)
+			path = append(path, pos)
+		}
+	}
+
+	if len(path) == 0 || path[len(path)-1] != to {
+		path = append(path, to)
+	}
+	a.NavPath = path
+}
+
+// navigate advances along the planned path at NavSpeed steps a second, returning the cursor index
+func (a *AnimState) navigate(deltaTime float32) int {
+	if a.GotoTimer > 0 {
+		a.GotoTimer -= deltaTime
+		return a.Cursor
+	}
+	a.GotoLine = 0
+
+	a.NavProgress += deltaTime * a.NavSpeed
+	for a.NavProgress >= 1 && len(a.NavPath) > 0 {
+		a.Cursor = a.NavPath[0]
+		a.NavPath = a.NavPath[1:]
+		a.NavProgress--
+	}
+
+	return a.Cursor
+}
+
+func lineStarts(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func lineCol(starts []int, idx int) (int, int) {
+	line := sort.SearchInts(starts, idx+1) - 1
+	line = max(line, 0)
+	return line, idx - starts[line]
+}
+
+// lineIndex is the index of col on line, clamped to the line's length like an editor's up/down keys
+func lineIndex(text string, starts []int, line, col int) int {
+	end := len(text)
+	if line+1 < len(starts) {
+		end = starts[line+1] - 1
+	}
+	return min(starts[line]+col, end)
+}
+
+func wordStops(text string, from, to int) []int {
+	stops := []int{}
+	i := from
+	for {
+		if to > from {
+			i = nextGrapheme(text, i)
+		} else {
+			i = prevGrapheme(text, i)
+		}
+		if i <= 0 || i >= len(text) || (to > from && i >= to) || (to < from && i <= to) {
+			break
+		}
+		if !isWordRune(runeBefore(text, i)) && isWordRune(runeAt(text, i)) {
+			stops = append(stops, i)
+		}
+	}
+	return append(stops, to)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
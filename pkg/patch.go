@@ -0,0 +1,344 @@
+package gitanimate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type patchHunk struct {
+	OldStart int
+	OldLines int
+	NewLines int
+	Lines    []string
+}
+
+type filePatch struct {
+	OldPath      string
+	NewPath      string
+	Hunks        []*patchHunk
+	OldNoNewline bool
+	NewNoNewline bool
+}
+
+const devNull = "/dev/null"
+
+// ReadFilePair builds a CommitFile from a before/after pair of files on disk
+func ReadFilePair(oldPath, newPath string) (*CommitFile, error) {
+	prev, err := os.ReadFile(oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", oldPath, err)
+	}
+
+	curr, err := os.ReadFile(newPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", newPath, err)
+	}
+
+	return &CommitFile{
+		FileName:       filepath.Base(newPath),
+		PrevContent:    string(prev),
+		CurrentContent: string(curr),
+	}, nil
+}
+
+// ReadPatchFile parses the unified diff at patchPath, see ParsePatch
+func ReadPatchFile(patchPath, baseDir string) ([]*CommitFile, error) {
+	f, err := os.Open(patchPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open patch %s: %v", patchPath, err)
+	}
+	defer f.Close()
+
+	return ParsePatch(f, baseDir)
+}
+
+// ParsePatch builds a CommitFile for every file touched by a unified diff.
+// If baseDir is set and contains the original file, the hunks are applied to it,
+// otherwise the before/after contents are reconstructed from the hunks alone.
+func ParsePatch(r io.Reader, baseDir string) ([]*CommitFile, error) {
+	patches, err := parseUnifiedDiff(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return patchFiles(patches, func(path string) (*string, error) {
+		return readOriginal(baseDir, path)
+	})
+}
+
+func patchFiles(patches []*filePatch, lookup func(path string) (*string, error)) ([]*CommitFile, error) {
+	commitFiles := []*CommitFile{}
+	for _, p := range patches {
+		fileName := p.NewPath
+		if fileName == devNull {
+			fileName = p.OldPath
+		}
+
+		var prevContent, currentContent string
+
+		var original *string
+		if p.OldPath != devNull {
+			var err error
+			original, err = lookup(p.OldPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if original != nil {
+			prevContent = *original
+			var err error
+			currentContent, err = p.apply(prevContent)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply patch to %s: %v", p.OldPath, err)
+			}
+		} else {
+			prevContent, currentContent = p.reconstruct()
+		}
+
+		commitFiles = append(commitFiles, &CommitFile{
+			FileName:       fileName,
+			PrevContent:    prevContent,
+			CurrentContent: currentContent,
+		})
+	}
+
+	return commitFiles, nil
+}
+
+func readOriginal(baseDir, oldPath string) (*string, error) {
+	if baseDir == "" || oldPath == devNull {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(baseDir, oldPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", oldPath, err)
+	}
+
+	ret := string(content)
+	return &ret, nil
+}
+
+func parseUnifiedDiff(r io.Reader) ([]*filePatch, error) {
+	patches := []*filePatch{}
+	var curr *filePatch
+	var hunk *patchHunk
+	var lastOp byte
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "--- ") && (hunk == nil || hunk.done()):
+			curr = &filePatch{OldPath: patchPath(line[4:])}
+			patches = append(patches, curr)
+			hunk = nil
+			lastOp = 0
+		case strings.HasPrefix(line, "+++ ") && curr != nil && hunk == nil:
+			curr.NewPath = patchPath(line[4:])
+		case strings.HasPrefix(line, "@@ "):
+			if curr == nil {
+				return nil, fmt.Errorf("line %d: hunk without file header", lineNo)
+			}
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			hunk = h
+			curr.Hunks = append(curr.Hunks, hunk)
+			lastOp = 0
+		case strings.HasPrefix(line, `\`) && curr != nil && hunk != nil:
+			//"\ No newline at end of file" applies to the previous line, anywhere else
+			//(a commit message say) a leading backslash means nothing
+			switch lastOp {
+			case '-':
+				curr.OldNoNewline = true
+			case '+':
+				curr.NewNoNewline = true
+			default:
+				curr.OldNoNewline = true
+				curr.NewNoNewline = true
+			}
+		case hunk != nil && !hunk.done():
+			if line == "" {
+				line = " "
+			}
+			switch line[0] {
+			case ' ', '-', '+':
+				lastOp = line[0]
+				hunk.Lines = append(hunk.Lines, line)
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", lineNo, line)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read patch: %v", err)
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file changes found in patch")
+	}
+
+	return patches, nil
+}
+
+func parseHunkHeader(line string) (*patchHunk, error) {
+	//@@ -l,s +l,s @@ optional section heading
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return nil, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	oldStart, oldLines, err := parseHunkRange(fields[1][1:])
+	if err != nil {
+		return nil, err
+	}
+
+	_, newLines, err := parseHunkRange(fields[2][1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &patchHunk{
+		OldStart: oldStart,
+		OldLines: oldLines,
+		NewLines: newLines,
+		Lines:    []string{},
+	}, nil
+}
+
+func parseHunkRange(s string) (int, int, error) {
+	start, count, found := strings.Cut(s, ",")
+	startN, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk range: %q", s)
+	}
+
+	countN := 1
+	if found {
+		countN, err = strconv.Atoi(count)
+		if err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk range: %q", s)
+		}
+	}
+
+	return startN, countN, nil
+}
+
+func patchPath(s string) string {
+	//strip trailing timestamps and the a/ b/ prefixes git adds
+	s, _, _ = strings.Cut(s, "\t")
+	if s == devNull {
+		return s
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+func (h *patchHunk) done() bool {
+	oldN, newN := 0, 0
+	for _, l := range h.Lines {
+		switch l[0] {
+		case ' ':
+			oldN++
+			newN++
+		case '-':
+			oldN++
+		case '+':
+			newN++
+		}
+	}
+	return oldN >= h.OldLines && newN >= h.NewLines
+}
+
+func (p *filePatch) reconstruct() (string, string) {
+	var prev, curr strings.Builder
+	for _, h := range p.Hunks {
+		for _, l := range h.Lines {
+			switch l[0] {
+			case ' ':
+				prev.WriteString(l[1:] + "\n")
+				curr.WriteString(l[1:] + "\n")
+			case '-':
+				prev.WriteString(l[1:] + "\n")
+			case '+':
+				curr.WriteString(l[1:] + "\n")
+			}
+		}
+	}
+
+	return p.trimNewlines(prev.String(), curr.String())
+}
+
+func (p *filePatch) apply(original string) (string, error) {
+	oldLines := strings.SplitAfter(original, "\n")
+	if len(oldLines) > 0 && oldLines[len(oldLines)-1] == "" {
+		oldLines = oldLines[:len(oldLines)-1]
+	}
+
+	var out strings.Builder
+	pos := 0
+	for _, h := range p.Hunks {
+		start := h.OldStart - 1
+		if h.OldLines == 0 {
+			start = h.OldStart
+		}
+		if start < pos || start > len(oldLines) {
+			return "", fmt.Errorf("hunk at line %d out of range", h.OldStart)
+		}
+
+		for _, l := range oldLines[pos:start] {
+			out.WriteString(l)
+		}
+
+		for _, l := range h.Lines {
+			switch l[0] {
+			case ' ', '-':
+				if start >= len(oldLines) || strings.TrimSuffix(oldLines[start], "\n") != l[1:] {
+					return "", fmt.Errorf("hunk at line %d does not match original", h.OldStart)
+				}
+				if l[0] == ' ' {
+					out.WriteString(oldLines[start])
+				}
+				start++
+			case '+':
+				out.WriteString(l[1:] + "\n")
+			}
+		}
+		pos = start
+	}
+
+	for _, l := range oldLines[pos:] {
+		out.WriteString(l)
+	}
+
+	_, curr := p.trimNewlines("", out.String())
+	return curr, nil
+}
+
+func (p *filePatch) trimNewlines(prev, curr string) (string, string) {
+	if p.OldNoNewline {
+		prev = strings.TrimSuffix(prev, "\n")
+	}
+	if p.NewNoNewline {
+		curr = strings.TrimSuffix(curr, "\n")
+	}
+	return prev, curr
+}
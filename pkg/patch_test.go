@@ -0,0 +1,96 @@
+package gitanimate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  []CommitFile
+	}{
+		{
+			name: "backslash in commit message",
+			patch: `From 1234567 Mon Sep 17 00:00:00 2001
+Subject: [PATCH] Escape paths
+
+\ is a path separator on windows
+\\server\share paths too
+---
+ main.go | 2 +-
+
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,2 @@
+ a
+-b
++c
+\ No newline at end of file
+`,
+			want: []CommitFile{{FileName: "main.go", PrevContent: "a\nb\n", CurrentContent: "a\nc"}},
+		},
+		{
+			name: "added and deleted files",
+			patch: `--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++package main
++func main() {}
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package old
+`,
+			want: []CommitFile{
+				{FileName: "new.go", CurrentContent: "package main\nfunc main() {}\n"},
+				{FileName: "old.go", PrevContent: "package old\n"},
+			},
+		},
+		{
+			name: "multiple files and hunks",
+			patch: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+-x
++y
+ z
+@@ -10 +10 @@
+-p
++q
+\ No newline at end of file
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -3 +3,2 @@
+ m
++n
+`,
+			want: []CommitFile{
+				{FileName: "a.go", PrevContent: "x\nz\np\n", CurrentContent: "y\nz\nq"},
+				{FileName: "b.go", PrevContent: "m\n", CurrentContent: "m\nn\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ParsePatch(strings.NewReader(tt.patch), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != len(tt.want) {
+				t.Fatalf("got %d files, want %d", len(files), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := files[i]
+				if got.FileName != want.FileName || got.PrevContent != want.PrevContent || got.CurrentContent != want.CurrentContent {
+					t.Errorf("file %d: got %q %q %q, want %q %q %q", i,
+						got.FileName, got.PrevContent, got.CurrentContent, want.FileName, want.PrevContent, want.CurrentContent)
+				}
+			}
+		})
+	}
+}
@@ -3,16 +3,10 @@ package gitanimate
 import (
 	"embed"
 	"fmt"
-	"math"
-	"math/rand"
 	"os"
-	"os/exec"
-	"os/signal"
 	"path"
-	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
@@ -28,18 +22,61 @@ import (
 )
 
 type AnimateParams struct {
-	Output        string
-	Font          string
-	Theme         string
-	MinDelay      float32
-	MaxDelay      float32
-	Width         int32
-	Height        int32
-	DisableRandom bool
+	Output         string
+	Font           string
+	Theme          string
+	MinDelay       float32
+	MaxDelay       float32
+	Width          int32
+	Height         int32
+	DisableRandom  bool
+	DiffMode       string
+	NavSpeed       float32
+	Seed           int64
+	TypingDist     string
+	WordPause      float32
+	TypoRate       float32
+	BurstFactor    float32
+	PasteLines     int
+	DeleteStyle    string
+	Summary        float32
+	View           string
+	Wrap           string
+	Establish      float32
+	Fold           int
+	FontFallback   []string
+	FontBold       string
+	FontItalic     string
+	FontBoldItalic string
+	FontSize       float32
+	LineHeight     float32
+	Padding        float32
+	FitWidth       int
+	TabWidth       int
+	ShowWhitespace bool
+	//hex colours overriding the theme, empty to keep it
+	GutterColor      string
+	LineNumberColor  string
+	CursorColor      string
+	SelectionColor   string
+	EditorConfig     *EditorConfig
+	Chrome           string
+	ChromePadding    float32
+	ChromeBackground []string
+	TerminalBefore   []string
+	TerminalAfter    []string
+	TerminalPrompt   string
+	CommitCaptions   bool
+	Captions         *CaptionTrack
+	Subtitles        string
+	Minimap          bool
+	//render at Scale times the output size, then scale each frame down
+	Scale float32
 }
 
 type AnimateDiffParams struct {
 	Diffs          []diffmatchpatch.Diff
+	Segments       []*Segment
 	PrevContent    string
 	Filename       string
 	Params         *AnimateParams
@@ -47,6 +84,16 @@ type AnimateDiffParams struct {
 	ShowWindow     bool
 	Pos            int
 	Total          int
+	//the changeset the file belongs to, nil when there's none
+	Commit *Changeset
+}
+
+// Segment is one step of a scripted animation, played after the previous one finishes
+type Segment struct {
+	Diffs   []diffmatchpatch.Diff
+	Speed   float32
+	Pause   float32
+	Caption string
 }
 
 type AnimState struct {
@@ -56,6 +103,38 @@ type AnimState struct {
 	Lang           string
 	Filename       string
 	UpdateProgress func(tea.Msg) (tea.Model, tea.Cmd)
+	//last rendered text and cursor, used to navigate between edits
+	Text        string
+	Cursor      int
+	PageLines   int
+	NavSpeed    float32
+	NavPath     []int
+	NavProgress float32
+	GotoLine    int
+	GotoTimer   float32
+	//mistyped key currently shown, and whether it has just been backspaced
+	Typo      string
+	TypoFixed bool
+	//inserts of at least PasteLines lines are pasted rather than typed
+	PasteLines int
+	Pasted     bool
+	PasteStart int
+	PasteEnd   int
+	//deleted text currently selected, empty when SelectEnd <= SelectStart
+	DeleteStyle string
+	SelectStart int
+	SelectEnd   int
+	Gutter      *gutterMarkers
+	//unchanged lines more than Fold lines from an edit are folded away, 0 disables folding
+	Fold  int
+	Folds []fold
+}
+
+type highlight struct {
+	Start  int
+	End    int
+	Color  rl.Color
+	Strike bool
 }
 
 var (
@@ -63,8 +142,9 @@ var (
 	fonts      embed.FS
 	font       rl.Font
 	style              = styles.Get("catppuccin-mocha")
-	fontSize   float32 = 20
-	lineHeight float32 = fontSize * 1.2
+	fontSize   float32 = DefaultFontSize
+	lineHeight float32 = fontSize * DefaultLineHeight
+	padding    float32 = DefaultPadding
 	Logger             = log.NewWithOptions(os.Stderr, log.Options{
 		//ReportCaller: true,
 		//Prefix:       "[gitanimate]",
@@ -87,11 +167,31 @@ var (
 )
 
 const (
-	FrameRate     = 10
-	FFmpegPath    = "ffmpeg"
-	FFmpegPreset  = "veryslow"
-	MaxFrameCount = 1000
-	FrameFormat   = "frame_%04d.png"
+	DeleteStyleSelect    = "select"
+	DeleteStyleStrike    = "strike"
+	DeleteStyleBackspace = "backspace"
+)
+
+const (
+	WrapOn  = "on"
+	WrapOff = "off"
+)
+
+// Resolutions are the output size presets, width by height
+var Resolutions = map[string][2]int32{
+	"1080p":    {1920, 1080},
+	"4k":       {3840, 2160},
+	"square":   {1080, 1080},
+	"vertical": {1080, 1920},
+}
+
+const (
+	PasteFlashDuration = 0.6
+	FrameRate          = 10
+	FFmpegPath         = "ffmpeg"
+	FFmpegPreset       = "veryslow"
+	MaxFrameCount      = 1000
+	FrameFormat        = "frame_%04d.png"
 )
 
 func tokenizeCode(lang string, code string) ([]chroma.Token, error) {
@@ -110,65 +210,163 @@ func tokenizeCode(lang string, code string) ([]chroma.Token, error) {
 	return tokens, nil
 }
 
-func loadFont(name string) {
-	if name == "default" {
-		f, err := fonts.ReadFile("assets/fonts/IBMPlexMono-Regular.ttf")
-		if err != nil {
-			Logger.Errorf("Failed to load font: %s", err)
-			font = rl.GetFontDefault()
-		}
-
-		font = rl.LoadFontFromMemory(".otf", f, 120, nil)
-	} else {
-		font = rl.LoadFontEx(name, 120, nil, 0)
-		if font.Texture.ID == rl.GetFontDefault().Texture.ID {
-			Logger.Errorf("Failed to load font %s, defaulting to IBM Plex Mono", name)
-			loadFont("default")
-		}
-	}
-}
-
 func getColorForTokenType(tokenType chroma.TokenType) rl.Color {
 	entry := style.Get(tokenType)
 	color := entry.Colour
 	return rl.Color{R: color.Red(), G: color.Green(), B: color.Blue(), A: 255}
 }
 
-func renderTokensAll(tokens []chroma.Token, startX, startY float32, cursorVisible bool, scrollOffsetY float32, cursorIndex int) (float32, float32) {
-	lineNumberWidth := float32(50)
-	x, y := startX+lineNumberWidth, startY
+// textStyle is how the theme draws a token, Background is transparent when it has none of its own
+type textStyle struct {
+	Color      rl.Color
+	Background rl.Color
+	Variant    int
+	Underline  bool
+}
+
+func getStyleForTokenType(tokenType chroma.TokenType) textStyle {
+	entry := style.Get(tokenType)
+	ts := textStyle{
+		Color:     getColorForTokenType(tokenType),
+		Underline: entry.Underline == chroma.Yes,
+	}
+	if entry.Bold == chroma.Yes {
+		ts.Variant |= variantBold
+	}
+	if entry.Italic == chroma.Yes {
+		ts.Variant |= variantItalic
+	}
+	if entry.Background.IsSet() && entry.Background != style.Get(chroma.Background).Background {
+		bg := entry.Background
+		ts.Background = rl.Color{R: bg.Red(), G: bg.Green(), B: bg.Blue(), A: 255}
+	}
+	return ts
+}
+
+func strikeColor() rl.Color {
+	if style.Get(chroma.GenericDeleted).Colour.IsSet() {
+		return getColorForTokenType(chroma.GenericDeleted)
+	}
+	return rl.Red
+}
+
+func pasteFlashColor(alpha float32) rl.Color {
+	entry := style.Get(chroma.GenericInserted)
+	if !entry.Colour.IsSet() {
+		return rl.Fade(rl.Green, alpha*0.4)
+	}
+	return rl.Fade(getColorForTokenType(chroma.GenericInserted), alpha*0.4)
+}
+
+func renderTokensAll(tokens []chroma.Token, view viewport, cursorVisible bool, scroll rl.Vector2, wrap bool, cursorIndex int, highlights []highlight, folds []fold, gutter *gutterMarkers) (float32, float32, float32) {
+	startX := view.X
+	textX := view.textX()
+	markerX := textX - gutterMarkerWidth*3
+	x, y := textX, view.Y
 	charsRendered := 0
 	var cursorX, cursorY float32 = x, y
 	lineNumber := 1
 
-	screenWidth := float32(rl.GetScreenWidth())
+	right := view.right()
+	scrollOffsetY := scroll.Y
+	if wrap {
+		scroll.X = 0
+	}
+
+	//leading whitespace of the current line, continuation rows are indented past it
+	indent := float32(0)
+	inIndent := true
+	wrapIndent := 2 * cellWidth()
 
-	lineNumberStr := fmt.Sprintf("%d", lineNumber)
-	rl.DrawTextEx(font, lineNumberStr, rl.Vector2{X: startX, Y: y - scrollOffsetY}, fontSize, 0, rl.Gray)
+	if ui.Gutter.A > 0 {
+		rl.DrawRectangleRec(rl.Rectangle{X: 0, Y: 0, Width: markerX + gutterMarkerWidth, Height: canvasHeight}, ui.Gutter)
+	}
+
+	//a line starting a fold gets the fold marker instead of its number
+	startLine := func(idx int) {
+		if f := foldAt(folds, idx); f != nil && f.Start == idx {
+			renderFoldMarker(f.Lines, textX, y-scrollOffsetY, right-textX)
+			return
+		}
+		lineNumberStr := fmt.Sprintf("%d", lineNumber)
+		rl.DrawTextEx(font, lineNumberStr, rl.Vector2{X: startX, Y: y - scrollOffsetY}, fontSize, 0, ui.LineNumber)
+		gutter.render(lineNumber-1, markerX, y-scrollOffsetY, right-markerX, true)
+	}
+	startLine(0)
 
 	currIdx := 0
 	for _, token := range tokens {
-		color := getColorForTokenType(token.Type)
+		ts := getStyleForTokenType(token.Type)
 		text := token.Value
 		for i, char := range text {
-			charStr := string(char)
+			if f := foldAt(folds, currIdx+i); f != nil {
+				if currIdx+i == cursorIndex {
+					cursorX, cursorY = textX, y
+				}
+				//folds end on a newline, pick up again on the line after
+				if currIdx+i == f.End-1 {
+					x = textX
+					y += lineHeight
+					lineNumber += f.Lines
+					startLine(f.End)
+				}
+				continue
+			}
+
 			if char == '\n' {
-				x = startX + lineNumberWidth
+				x = textX
 				y += lineHeight
+				indent = 0
+				inIndent = true
 				lineNumber++
-				lineNumberStr := fmt.Sprintf("%d", lineNumber)
-				rl.DrawTextEx(font, lineNumberStr, rl.Vector2{X: startX, Y: y - scrollOffsetY}, fontSize, 0, rl.Gray)
+				startLine(currIdx + i + 1)
 			} else {
-				charWidth := rl.MeasureTextEx(font, charStr, fontSize, 0).X
+				charWidth := runeWidth(char)
+				if char == '\t' {
+					charWidth = tabAdvance(x - textX)
+				}
+
+				if inIndent && (char == ' ' || char == '\t') {
+					indent += charWidth
+				} else {
+					inIndent = false
+				}
 
-				if x+charWidth > screenWidth-10 {
-					x = startX + lineNumberWidth
+				contX := textX + min(indent+wrapIndent, (right-textX)/2)
+				if wrap && x+charWidth > right && x > contX {
+					x = contX
 					y += lineHeight
-					lineNumberStr := ""
-					rl.DrawTextEx(font, lineNumberStr, rl.Vector2{X: startX, Y: y - scrollOffsetY}, fontSize, 0, rl.Gray)
+					renderWrapIndicator(x-wrapIndent, y-scrollOffsetY)
+					gutter.render(lineNumber-1, markerX, y-scrollOffsetY, right-markerX, false)
 				}
 
-				rl.DrawTextEx(font, charStr, rl.Vector2{X: x, Y: y - scrollOffsetY}, fontSize, 0, color)
+				//with wrapping off the text pans under the line numbers, so hide whatever scrolls past them
+				drawX := x - scroll.X
+				if drawX >= textX && drawX < right {
+					if ts.Background.A > 0 {
+						rl.DrawRectangleRec(rl.Rectangle{X: drawX, Y: y - scrollOffsetY, Width: charWidth, Height: lineHeight}, ts.Background)
+					}
+					for _, h := range highlights {
+						if currIdx+i >= h.Start && currIdx+i < h.End && !h.Strike {
+							rl.DrawRectangleRec(rl.Rectangle{X: drawX, Y: y - scrollOffsetY, Width: charWidth, Height: lineHeight}, h.Color)
+						}
+					}
+
+					if showWhitespace && (char == ' ' || char == '\t') {
+						renderWhitespaceMarker(char, drawX, y-scrollOffsetY, charWidth)
+					} else {
+						drawStyledRune(char, drawX, y-scrollOffsetY, ts.Color, ts.Variant)
+					}
+					if ts.Underline {
+						rl.DrawRectangleRec(rl.Rectangle{X: drawX, Y: y - scrollOffsetY + fontSize, Width: charWidth, Height: 1}, ts.Color)
+					}
+
+					for _, h := range highlights {
+						if currIdx+i >= h.Start && currIdx+i < h.End && h.Strike {
+							rl.DrawRectangleRec(rl.Rectangle{X: drawX, Y: y - scrollOffsetY + fontSize/2, Width: charWidth, Height: 2}, h.Color)
+						}
+					}
+				}
 				x += charWidth
 			}
 			charsRendered++
@@ -180,11 +378,43 @@ func renderTokensAll(tokens []chroma.Token, startX, startY float32, cursorVisibl
 		currIdx += len(text)
 	}
 
-	if cursorVisible {
-		rl.DrawRectangle(int32(cursorX), int32(cursorY-scrollOffsetY), 2, int32(lineHeight/1.4), rl.White)
+	if cursorVisible && cursorX-scroll.X >= textX {
+		rl.DrawRectangle(int32(cursorX-scroll.X), int32(cursorY-scrollOffsetY), 2, int32(lineHeight/1.4), ui.Cursor)
 	}
 
-	return cursorX, cursorY
+	return cursorX, cursorY, y + lineHeight
+}
+
+// renderWrapIndicator draws a small hooked arrow in front of a continuation row
+func renderWrapIndicator(x, y float32) {
+	color := rl.Fade(ui.LineNumber, 0.6)
+	size := fontSize * 0.4
+	midY := y + lineHeight/2
+	rl.DrawLineEx(rl.Vector2{X: x + 2, Y: midY - size}, rl.Vector2{X: x + 2, Y: midY}, 1.5, color)
+	rl.DrawLineEx(rl.Vector2{X: x + 2, Y: midY}, rl.Vector2{X: x + size*2, Y: midY}, 1.5, color)
+	rl.DrawTriangle(
+		rl.Vector2{X: x + size*2 + 3, Y: midY},
+		rl.Vector2{X: x + size*2 - 1, Y: midY - 3},
+		rl.Vector2{X: x + size*2 - 1, Y: midY + 3},
+		color,
+	)
+}
+
+func renderGotoLine(line int) {
+	text := fmt.Sprintf(":%d", line)
+	screenWidth := canvasWidth
+	size := rl.MeasureTextEx(font, text, fontSize, 0)
+	padding := fontSize / 2
+	width := max(size.X+padding*2, screenWidth/3)
+	x := (screenWidth - width) / 2
+
+	rl.DrawRectangleRounded(rl.Rectangle{
+		X:      x,
+		Y:      padding,
+		Width:  width,
+		Height: size.Y + padding*2,
+	}, 0.3, 8, rl.Fade(rl.Black, 0.8))
+	rl.DrawTextEx(font, text, rl.Vector2{X: x + padding, Y: padding * 2}, fontSize, 0, rl.White)
 }
 
 func (a *AnimState) incr() bool {
@@ -207,8 +437,16 @@ func (a *AnimState) incr() bool {
 		a.OpIndex++
 	}
 
-	a.CharIndex++
-	if a.CharIndex >= len(a.Diffs[a.OpIndex].Text) {
+	text := a.Diffs[a.OpIndex].Text
+	if a.pasting() {
+		//paste big blocks in one go instead of typing them out
+		a.CharIndex = max(prevGrapheme(text, len(text)), 1)
+		a.Pasted = true
+		return false
+	}
+
+	a.CharIndex = nextGrapheme(text, a.CharIndex)
+	if a.CharIndex >= len(text) {
 		a.CharIndex = 0
 		for {
 			a.OpIndex++
@@ -226,24 +464,55 @@ func (a *AnimState) incr() bool {
 	return false
 }
 
-func (a *AnimState) renderTokens(disableRandom bool) ([]chroma.Token, int) {
+// pasting is whether the current op is an insert long enough to be pasted rather than typed
+func (a *AnimState) pasting() bool {
+	if a.OpIndex >= len(a.Diffs) || a.CharIndex != 0 || a.PasteLines <= 0 {
+		return false
+	}
+	op := a.Diffs[a.OpIndex]
+	return op.Type == diffmatchpatch.DiffInsert && strings.Count(op.Text, "\n") >= a.PasteLines
+}
+
+func (a *AnimState) renderTokens() ([]chroma.Token, int) {
 	if a.OpIndex >= len(a.Diffs) {
 		a.OpIndex = len(a.Diffs) - 1
 	}
 
 	cursorIndex := 0
 	str := ""
+	a.SelectStart, a.SelectEnd = 0, 0
+	inserted := []highlight{}
+	deleted := []deletion{}
+	a.Folds = []fold{}
+	foldEdits := a.Fold > 0 && hasEdits(a.Diffs)
+	addFold := func(i int) {
+		if !foldEdits {
+			return
+		}
+		lineStart := len(str) == 0 || str[len(str)-1] == '\n'
+		if f := foldEqual(a.Diffs[i].Text, len(str), lineStart, a.Fold, i > 0, i < len(a.Diffs)-1); f != nil {
+			a.Folds = append(a.Folds, *f)
+		}
+	}
 	//prior character-wise changes
 	for i := 0; i < a.OpIndex; i++ {
 		switch a.Diffs[i].Type {
 		case diffmatchpatch.DiffEqual:
+			addFold(i)
 			str += a.Diffs[i].Text
 		case diffmatchpatch.DiffInsert:
+			inserted = append(inserted, highlight{Start: len(str), End: len(str) + len(a.Diffs[i].Text)})
 			str += a.Diffs[i].Text
+		case diffmatchpatch.DiffDelete:
+			deleted = append(deleted, deletion{
+				Pos:        len(str),
+				WholeLines: strings.HasSuffix(a.Diffs[i].Text, "\n") && (len(str) == 0 || str[len(str)-1] == '\n'),
+			})
 		}
 	}
 
 	if a.OpIndex >= len(a.Diffs) {
+		a.Text = str
 		tokens, err := tokenizeCode(a.Lang, str)
 		if err != nil {
 			Logger.Fatal(err)
@@ -256,52 +525,68 @@ func (a *AnimState) renderTokens(disableRandom bool) ([]chroma.Token, int) {
 	switch a.Diffs[a.OpIndex].Type {
 	case diffmatchpatch.DiffInsert:
 		//jumps whitespace at 2x speed
-		if whitespace.IsWhitespace(rune(a.Diffs[a.OpIndex].Text[a.CharIndex])) {
-			a.CharIndex++
-			if a.CharIndex >= len(a.Diffs[a.OpIndex].Text) {
-				a.CharIndex--
-			}
-			if random(0, 1) > 0.9 && !disableRandom {
-				time.Sleep(time.Duration(random(200, 500)) * time.Millisecond)
+		text := a.Diffs[a.OpIndex].Text
+		if whitespace.IsWhitespace(runeAt(text, a.CharIndex)) {
+			if next := nextGrapheme(text, a.CharIndex); next < len(text) {
+				a.CharIndex = next
 			}
 		}
 
 		cursorIndex = len(str) + a.CharIndex
+		a.PasteStart = len(str)
 		str += a.Diffs[a.OpIndex].Text[:a.CharIndex]
+		a.PasteEnd = len(str)
+		inserted = append(inserted, highlight{Start: a.PasteStart, End: a.PasteEnd})
+		if a.Typo != "" {
+			str += a.Typo
+			cursorIndex += len(a.Typo)
+		}
 		if a.Diffs[a.OpIndex].Text[len(a.Diffs[a.OpIndex].Text)-1] == byte("\n"[0]) {
 			str += "\n"
-			cursorIndex -= 1
+			cursorIndex = prevGrapheme(str, cursorIndex)
 		}
 	case diffmatchpatch.DiffDelete:
-		//jump deletes word-wise
-		if whitespace.IsWhitespace(rune(a.Diffs[a.OpIndex].Text[a.CharIndex])) {
-			for a.CharIndex < len(a.Diffs[a.OpIndex].Text) && whitespace.IsWhitespace(rune(a.Diffs[a.OpIndex].Text[a.CharIndex])) {
-				a.CharIndex++
+		text := a.Diffs[a.OpIndex].Text
+
+		if a.DeleteStyle == DeleteStyleBackspace {
+			//jump whitespace runs, and long deletes a line at a time
+			end := graphemeFloor(text, len(text)-a.CharIndex)
+			if strings.Count(text, "\n") > 3 {
+				end = strings.LastIndex(text[:max(end-1, 0)], "\n") + 1
+			} else {
+				for end > 0 && whitespace.IsWhitespace(runeBefore(text, end)) {
+					end = prevGrapheme(text, end)
+				}
 			}
-		}
+			a.CharIndex = len(text) - end
 
-		lines := strings.Split(a.Diffs[a.OpIndex].Text, "\n")
-		if len(lines) > 3 {
-			//delete in line chunks
+			str += text[:end]
 			cursorIndex = len(str)
-			a.CharIndex = a.CharIndex + len(lines[0]) + 1
-			str = str[:len(str)-a.CharIndex]
 			break
 		}
 
-		cursorIndex = len(str) - a.CharIndex
-		str = str[:len(str)-a.CharIndex]
-
-		if cursorIndex >= len(str) {
-			cursorIndex = len(str) - 1
+		//grow a selection over the deleted text a word, or a line, at a time
+		last := prevGrapheme(text, len(text))
+		a.CharIndex = graphemeFloor(text, a.CharIndex)
+		if a.CharIndex > 0 && a.CharIndex < last {
+			if idx := strings.Index(text[a.CharIndex:], "\n"); idx >= 0 && strings.Count(text, "\n") > 1 {
+				a.CharIndex += idx
+			} else {
+				for a.CharIndex < last && isWordRune(runeAt(text, a.CharIndex)) {
+					a.CharIndex = nextGrapheme(text, a.CharIndex)
+				}
+			}
 		}
+		a.CharIndex = min(a.CharIndex, last)
 
-		if str[cursorIndex] == "\n"[0] {
-			cursorIndex = max(cursorIndex-1, 0)
-		}
+		a.SelectStart = len(str)
+		a.SelectEnd = len(str) + nextGrapheme(text, a.CharIndex)
+		cursorIndex = a.SelectEnd
+		str += text
 	case diffmatchpatch.DiffEqual:
 		cursorIndex = len(str)
 		a.CharIndex = len(a.Diffs[a.OpIndex].Text)
+		addFold(a.OpIndex)
 		str += a.Diffs[a.OpIndex].Text
 	}
 
@@ -309,12 +594,15 @@ func (a *AnimState) renderTokens(disableRandom bool) ([]chroma.Token, int) {
 	for i := a.OpIndex + 1; i < len(a.Diffs); i++ {
 		switch a.Diffs[i].Type {
 		case diffmatchpatch.DiffEqual:
+			addFold(i)
 			str += a.Diffs[i].Text
 		case diffmatchpatch.DiffDelete:
 			str += a.Diffs[i].Text
 		}
 	}
 
+	a.Text = str
+	a.Gutter = computeGutter(str, inserted, deleted)
 	tokens, err := tokenizeCode(a.Lang, str)
 	if err != nil {
 		Logger.Fatal(err)
@@ -323,84 +611,161 @@ func (a *AnimState) renderTokens(disableRandom bool) ([]chroma.Token, int) {
 	return tokens, cursorIndex
 }
 
+// CheckParams validates the options picked from a fixed set, filling in the defaults of any left empty
+func CheckParams(params *AnimateParams) error {
+	switch params.DiffMode {
+	case DiffModeChar, DiffModeWord, DiffModeLine:
+	case "":
+		params.DiffMode = DiffModeChar
+	default:
+		return fmt.Errorf("unknown diff mode %q, expected one of %s, %s or %s", params.DiffMode, DiffModeLine, DiffModeWord, DiffModeChar)
+	}
+
+	switch params.TypingDist {
+	case TypingDistExp, TypingDistLogNormal, TypingDistUniform:
+	case "":
+		params.TypingDist = TypingDistExp
+	default:
+		return fmt.Errorf("unknown typing distribution %q, expected one of %s, %s or %s",
+			params.TypingDist, TypingDistExp, TypingDistLogNormal, TypingDistUniform)
+	}
+
+	switch params.DeleteStyle {
+	case DeleteStyleSelect, DeleteStyleStrike, DeleteStyleBackspace:
+	case "":
+		params.DeleteStyle = DeleteStyleSelect
+	default:
+		return fmt.Errorf("unknown delete style %q, expected one of %s, %s or %s",
+			params.DeleteStyle, DeleteStyleSelect, DeleteStyleStrike, DeleteStyleBackspace)
+	}
+
+	switch params.View {
+	case ViewType, ViewSplit, ViewUnified:
+	case "":
+		params.View = ViewType
+	default:
+		return fmt.Errorf("unknown view %q, expected one of %s, %s or %s", params.View, ViewType, ViewSplit, ViewUnified)
+	}
+
+	switch params.Wrap {
+	case WrapOn, WrapOff:
+	case "":
+		params.Wrap = WrapOn
+	default:
+		return fmt.Errorf("unknown wrap mode %q, expected %s or %s", params.Wrap, WrapOn, WrapOff)
+	}
+
+	switch params.Subtitles {
+	case "", SubtitlesSRT, SubtitlesVTT:
+	default:
+		return fmt.Errorf("unknown subtitle format %q, expected %s or %s", params.Subtitles, SubtitlesSRT, SubtitlesVTT)
+	}
+
+	return checkChrome(params)
+}
+
 func AnimateDiff(params *AnimateDiffParams) error {
-	err := os.MkdirAll(params.Params.Output, os.ModePerm)
+	if err := os.MkdirAll(params.Params.Output, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
 
-	temp, err := os.MkdirTemp("", "gitanimate")
+	if params.Params.View == ViewSplit || params.Params.View == ViewUnified {
+		return animateDiffView(params)
+	}
+
+	recorder, err := newFrameRecorder()
 	if err != nil {
 		Logger.Fatal(err)
 	}
-	defer os.RemoveAll(temp)
+	defer recorder.close()
+	recorder.subtitles = params.Params.Subtitles
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	wrap := params.Params.Wrap != WrapOff
 
-	go func() {
-		<-c
-		Logger.Info("Cleaning up temporary files")
-		err := os.RemoveAll(temp)
-		if err != nil {
-			Logger.Errorf("Failed to clean up temporary files: %v", err)
-		}
+	//the segments are the caller's, don't touch them
+	segments := slices.Clone(params.Segments)
+	if len(segments) == 0 {
+		segments = []*Segment{{Diffs: params.Diffs, Speed: 1}}
+	}
 
-		os.Exit(1)
-	}()
+	totalOps := 0
+	for i, seg := range segments {
+		//the state machine finishes on the last op, so make sure that's an equal one
+		if n := len(seg.Diffs); n == 0 || seg.Diffs[n-1].Type != diffmatchpatch.DiffEqual {
+			padded := *seg
+			padded.Diffs = append(slices.Clone(seg.Diffs), diffmatchpatch.Diff{Type: diffmatchpatch.DiffEqual})
+			segments[i] = &padded
+		}
+		totalOps += len(segments[i].Diffs)
+	}
 
-	bar := progressbar.NewOptions(len(params.Diffs),
+	bar := progressbar.NewOptions(totalOps,
 		progressbar.OptionEnableColorCodes(true),
 		progressbar.OptionSetWidth(45),
 		progressbar.OptionSetDescription(params.Filename+fmt.Sprintf(" (%d/%d) ", params.Pos, params.Total)),
 	)
 
-	rl.SetTraceLogLevel(rl.LogError)
-	var flags uint32 = rl.FlagVsyncHint | rl.FlagWindowHighdpi
-	if !params.ShowWindow {
-		flags |= rl.FlagWindowHidden
-	}
-	rl.SetConfigFlags(flags)
-	rl.InitWindow(params.Params.Width, params.Params.Height, "gitanimate")
-	defer rl.CloseWindow()
-
-	//renderTexture := rl.LoadRenderTexture(ScreenWidth, ScreenHeight)
-	//defer rl.UnloadRenderTexture(renderTexture)
-
-	rl.SetTargetFPS(FrameRate)
+	clip := newClipCaptions(params)
+	title := chromeTitle(params)
+	bgRl := openWindow(params.Params, params.ShowWindow, windowText(params, segments)+title+terminalText(params)+clip.text())
+	defer closeWindow()
+	recorder.chrome = newChrome(params.Params, title, bgRl)
 
-	style = styles.Get(params.Params.Theme)
-	bg := style.Get(chroma.Background)
-	bgRl := rl.Color{R: bg.Background.Red(), G: bg.Background.Green(), B: bg.Background.Blue(), A: 255}
-
-	loadFont(params.Params.Font)
+	applyTabWidth(params)
+	maxLines := max(lineCount(params.PrevContent), lineCount(currentContent(params)))
+	if params.Params.FitWidth > 0 {
+		fitFontSize(params.Params.FitWidth, maxLines)
+	}
+	view := newViewport(maxLines)
 
-	var scrollOffsetY float32
+	var cam camera
 
-	var nextCharTimer float32
-	var minDelay float32 = params.Params.MinDelay
-	var maxDelay float32 = params.Params.MaxDelay
+	typing := NewTypingModel(params.Params, params.Filename)
+	terminalTyping := newTerminalTyping(params)
 
-	nextCharTimer = random(minDelay, maxDelay)
+	nextCharTimer := typing.Delay(0, 0)
 
 	tokens, err := tokenizeCode(lang(params.Filename), params.PrevContent)
 	if err != nil {
 		Logger.Fatal(err)
 	}
 
+	segIdx := 0
+	segOps := 0
 	state := AnimState{
 		OpIndex:        0,
 		CharIndex:      0,
-		Diffs:          params.Diffs,
+		Diffs:          segments[segIdx].Diffs,
 		Lang:           lang(params.Filename),
 		Filename:       params.Filename,
 		UpdateProgress: params.UpdateProgress,
+		Text:           params.PrevContent,
+		PageLines:      int(view.Height / lineHeight),
+		NavSpeed:       params.Params.NavSpeed,
+		PasteLines:     params.Params.PasteLines,
+		DeleteStyle:    params.Params.DeleteStyle,
+		Fold:           params.Params.Fold,
+		Folds:          foldDiffs(segments[segIdx].Diffs, params.Params.Fold),
 	}
 
-	cursorIndex := 0
-	frameCount := 0
+	var flash highlight
+	var flashTimer float32
+
+	//open on the first edit rather than the top of the file
+	start := firstEdit(segments[0].Diffs)
+	cursorIndex := max(start-1, 0)
+	state.Cursor = cursorIndex
+	cam.frame(params.PrevContent, start, state.Folds, view.Y, canvasHeight)
+
+	renderTerminalScene(terminalCommands(params.Params.TerminalBefore, params), terminalPrompt(params.Params), terminalTyping, bgRl, recorder)
+
+	if params.Params.Establish > 0 {
+		renderEstablishingShot(tokens, params.PrevContent, state.Folds, view, params.Params.Establish, &cam, wrap, bgRl, recorder)
+	}
 
 	done := false
 	postDone := 0
 
-	var wg sync.WaitGroup
 	for !rl.WindowShouldClose() {
 		if done {
 			postDone++
@@ -408,115 +773,137 @@ func AnimateDiff(params *AnimateDiffParams) error {
 
 		deltaTime := rl.GetFrameTime()
 
-		nextCharTimer -= deltaTime
-		if nextCharTimer <= 0 {
-			done = state.incr()
+		if state.navigating() {
+			cursorIndex = state.navigate(deltaTime)
+		} else {
+			nextCharTimer -= deltaTime
+		}
 
-			bar.Set(state.OpIndex)
+		if nextCharTimer <= 0 && !state.navigating() {
+			prevOpIndex := state.OpIndex
 
-			tokens, cursorIndex = state.renderTokens(params.Params.DisableRandom)
-			if params.Params.DisableRandom {
-				nextCharTimer = minDelay
+			typo, delay := state.stepTypo(typing)
+			if typo {
+				tokens, cursorIndex = state.renderTokens()
+				state.Cursor = cursorIndex
+				nextCharTimer = delay
 			} else {
-				nextCharTimer =
-					float32(math.Exp(float64(random(-10, 0))))*(maxDelay-minDelay) +
-						minDelay
-				if nextCharTimer > 0.9*maxDelay {
-					nextCharTimer = maxDelay
+				done = state.incr()
+
+				bar.Set(segOps + state.OpIndex)
+
+				tokens, cursorIndex = state.renderTokens()
+				if state.OpIndex != prevOpIndex && !done {
+					//walk the cursor over to the new edit before typing it
+					state.planNavigation(state.Cursor, cursorIndex)
+					if state.navigating() {
+						cursorIndex = state.Cursor
+					}
+				}
+				state.Cursor = cursorIndex
+
+				nextCharTimer = typing.Delay(state.keys())
+				if state.Pasted {
+					state.Pasted = false
+					flash = highlight{Start: state.PasteStart, End: state.PasteEnd}
+					flashTimer = PasteFlashDuration
+					nextCharTimer = PasteFlashDuration
 				}
 			}
 
+			if segments[segIdx].Speed > 0 {
+				nextCharTimer /= segments[segIdx].Speed
+			}
+
+			if done && segIdx+1 < len(segments) {
+				//hold on the finished segment, then start typing the next one
+				nextCharTimer += segments[segIdx].Pause
+				segOps += len(segments[segIdx].Diffs)
+				segIdx++
+				state.Diffs = segments[segIdx].Diffs
+				state.OpIndex = 0
+				state.CharIndex = 0
+				done = false
+			}
 		}
 
-		rl.BeginDrawing()
-		//rl.BeginTextureMode(renderTexture)
-		rl.ClearBackground(bgRl)
+		beginFrame(bgRl)
 
-		_, cursorY := renderTokensAll(tokens, 10, 10, true, scrollOffsetY, cursorIndex)
+		highlights := []highlight{}
+		if flashTimer > 0 {
+			flash.Color = pasteFlashColor(flashTimer / PasteFlashDuration)
+			highlights = append(highlights, flash)
+			flashTimer -= deltaTime
+		}
 
-		windowHeight := float32(rl.GetScreenHeight())
-		linesFromBottom := lineHeight * 2
+		if state.SelectEnd > state.SelectStart {
+			if state.DeleteStyle == DeleteStyleStrike {
+				highlights = append(highlights, highlight{Start: state.SelectStart, End: state.SelectEnd, Color: strikeColor(), Strike: true})
+			} else {
+				highlights = append(highlights, highlight{Start: state.SelectStart, End: state.SelectEnd, Color: ui.Selection})
+			}
+		}
 
-		if cursorY-scrollOffsetY+linesFromBottom > windowHeight {
-			scrollOffsetY = cursorY + linesFromBottom - windowHeight + windowHeight/2
+		gutter := state.Gutter
+		if done && params.Params.Summary > 0 && gutter != nil {
+			gutter.Summary = true
 		}
 
-		//rl.EndTextureMode()
-		rl.EndDrawing()
+		cursorX, cursorY, contentHeight := renderTokensAll(tokens, view, true, rl.Vector2{X: cam.X, Y: cam.Y}, wrap, cursorIndex, highlights, state.Folds, gutter)
+		renderMinimap(tokens, cam.Y, contentHeight, cursorIndex, highlights, gutter)
 
-		img := rl.LoadImageFromScreen()
-		//img := rl.LoadImageFromTexture(renderTexture.Texture)
-		if img == nil {
-			Logger.Fatal("Failed to load image from screen")
+		captions := clip.at(float32(recorder.frameCount) / FrameRate)
+		if segments[segIdx].Caption != "" {
+			captions = append(captions, caption{Text: segments[segIdx].Caption})
 		}
+		renderCaptions(captions)
+		recorder.showCaptions(captions)
 
-		//rl.ImageFlipVertical(img)
+		if state.GotoLine > 0 {
+			renderGotoLine(state.GotoLine)
+		}
 
-		go func() {
-			wg.Add(1)
-			imgPath := filepath.Join(temp, fmt.Sprintf(FrameFormat, frameCount))
-			if !rl.ExportImage(*img, imgPath) {
-				rl.UnloadImage(img)
-				Logger.Fatal(err)
-			}
-			rl.UnloadImage(img)
+		cam.follow(cursorY, canvasHeight, contentHeight)
+		if !wrap {
+			cam.followX(cursorX, view.textX(), view.right())
+		}
+		cam.update(deltaTime)
 
-			wg.Done()
-		}()
+		endFrame()
+		recorder.capture()
 
 		//add extra frames at end to catch any missed changes
-		if postDone > FrameRate/2 || frameCount > MaxFrameCount {
+		if postDone > FrameRate/2+int((segments[segIdx].Pause+params.Params.Summary)*FrameRate) || recorder.clipFrames() > MaxFrameCount {
 			break
 		}
-
-		frameCount++
-
-		//time.Sleep(time.Second / FrameRate)
 	}
 
-	wg.Wait()
+	renderTerminalScene(terminalCommands(params.Params.TerminalAfter, params), terminalPrompt(params.Params), terminalTyping, bgRl, recorder)
 
-	if err := encodeFramesToVideo(
-		temp, frameCount+10,
-		path.Join(params.Params.Output, strings.ReplaceAll(params.Filename, "/", "_"))+".mp4",
-	); err != nil {
+	if err := recorder.encode(clipPath(params)); err != nil {
 		Logger.Fatal(err)
 	}
 
-	bar.Set(len(params.Diffs))
+	bar.Set(totalOps)
 	bar.Clear()
 	return nil
 }
 
-func encodeFramesToVideo(temp string, frameCount int, output string) error {
-	if frameCount == 0 {
-		return fmt.Errorf("no frames captured to encode")
-	}
-
-	inputPattern := filepath.Join(temp, FrameFormat)
-
-	cmd := exec.Command(
-		FFmpegPath,
-		"-y",
-		"-framerate", fmt.Sprintf("%d", FrameRate),
-		"-i", inputPattern,
-		"-c:v", "libx264",
-		"-preset", FFmpegPreset,
-		"-crf", "18",
-		"-pix_fmt", "yuv420p",
-		output,
-	)
-
-	//cmd.Stdout = os.Stdout
-	//cmd.Stderr = os.Stderr
-
-	//Logger.Debug("Starting FFmpeg encoding...")
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("FFmpeg error: %v", err)
+// windowText is everything a clip can show: the original file, every edit and the captions
+func windowText(params *AnimateDiffParams, segments []*Segment) string {
+	var b strings.Builder
+	b.WriteString(params.PrevContent)
+	for _, seg := range segments {
+		for _, d := range seg.Diffs {
+			b.WriteString(d.Text)
+		}
+		b.WriteString(seg.Caption)
 	}
+	return b.String()
+}
 
-	return nil
+func clipPath(params *AnimateDiffParams) string {
+	return path.Join(params.Params.Output, strings.ReplaceAll(params.Filename, "/", "_")) + ".mp4"
 }
 
 func lang(filename string) string {
@@ -531,7 +918,3 @@ func lang(filename string) string {
 
 	return ret
 }
-
-func random(min, max float32) float32 {
-	return min + rand.Float32()*(max-min)
-}
@@ -0,0 +1,60 @@
+package gitanimate
+
+import (
+	"testing"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+func TestAnimStateGraphemes(t *testing.T) {
+	diffs := []diffmatchpatch.Diff{
+		{Type: diffmatchpatch.DiffEqual, Text: "x := \""},
+		{Type: diffmatchpatch.DiffDelete, Text: "cafe\u0301 \u65e5\u672c"},
+		{Type: diffmatchpatch.DiffInsert, Text: "\u00e9te\u0301 \u65e5\n"},
+		{Type: diffmatchpatch.DiffEqual, Text: "\"\n"},
+	}
+
+	for _, style := range []string{DeleteStyleSelect, DeleteStyleStrike, DeleteStyleBackspace} {
+		a := &AnimState{Diffs: diffs, Lang: "go", DeleteStyle: style}
+		for step := 0; ; step++ {
+			if step > 100 {
+				t.Fatalf("%s: animation didn't finish", style)
+			}
+
+			text := a.Diffs[a.OpIndex].Text
+			if a.CharIndex != graphemeFloor(text, a.CharIndex) {
+				t.Errorf("%s: step %d: char index %d splits a grapheme in %q", style, step, a.CharIndex, text)
+			}
+
+			_, cursor := a.renderTokens()
+			if cursor < 0 || cursor > len(a.Text) || cursor != graphemeFloor(a.Text, cursor) {
+				t.Errorf("%s: step %d: cursor %d splits a grapheme in %q", style, step, cursor, a.Text)
+			}
+			if a.SelectEnd > a.SelectStart && (a.SelectStart != graphemeFloor(a.Text, a.SelectStart) || a.SelectEnd != graphemeFloor(a.Text, a.SelectEnd)) {
+				t.Errorf("%s: step %d: selection %d-%d splits a grapheme in %q", style, step, a.SelectStart, a.SelectEnd, a.Text)
+			}
+
+			if a.incr() {
+				break
+			}
+		}
+	}
+}
+
+func TestNoTypoBeforePaste(t *testing.T) {
+	diffs := []diffmatchpatch.Diff{
+		{Type: diffmatchpatch.DiffInsert, Text: "one\ntwo\nthree\n"},
+		{Type: diffmatchpatch.DiffEqual, Text: "end\n"},
+	}
+	typing := NewTypingModel(&AnimateParams{MinDelay: 0.05, MaxDelay: 0.2, TypoRate: 1}, "paste.go")
+
+	a := &AnimState{Diffs: diffs, Lang: "go", PasteLines: 3}
+	if typo, _ := a.stepTypo(typing); typo {
+		t.Fatalf("typed %q before a block that gets pasted", a.Typo)
+	}
+
+	a.PasteLines = 4
+	if typo, _ := a.stepTypo(typing); !typo {
+		t.Fatal("expected a typo at a typo rate of 1 when the block is typed")
+	}
+}
@@ -0,0 +1,288 @@
+package gitanimate
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Changeset struct {
+	ID      string
+	Message string
+	Author  string
+	Time    time.Time
+	Files   []*CommitFile
+}
+
+// Source yields changesets in order, returning io.EOF once exhausted
+type Source interface {
+	Next() (*Changeset, error)
+	Len() int
+}
+
+// GitWrap steps through a repository's commits one at a time, GitWrapper still implements it
+//
+// Deprecated: use Source, which works for every input and not just git
+type GitWrap interface {
+	GetFiles() ([]*CommitFile, error)
+	PopCommit() (string, error)
+	CurrCommit() string
+}
+
+var (
+	_ Source  = (*GitWrapper)(nil)
+	_ GitWrap = (*GitWrapper)(nil)
+)
+
+type limitSource struct {
+	src   Source
+	limit int
+	count int
+}
+
+type filesSource struct {
+	changeset *Changeset
+	done      bool
+}
+
+type PatchSeriesSource struct {
+	Patches  []string
+	BaseDir  string
+	Idx      int
+	contents map[string]*string
+}
+
+type SnapshotSource struct {
+	Snapshots []string
+	Idx       int
+}
+
+func LimitSource(src Source, limit int) Source {
+	return &limitSource{src: src, limit: limit}
+}
+
+func (l *limitSource) Next() (*Changeset, error) {
+	if l.count >= l.limit {
+		return nil, io.EOF
+	}
+	l.count++
+	return l.src.Next()
+}
+
+func (l *limitSource) Len() int {
+	return min(l.src.Len(), l.limit)
+}
+
+// NewFilesSource wraps a fixed set of files as a single changeset
+func NewFilesSource(id string, files []*CommitFile) Source {
+	return &filesSource{changeset: &Changeset{ID: id, Files: files}}
+}
+
+func (f *filesSource) Next() (*Changeset, error) {
+	if f.done {
+		return nil, io.EOF
+	}
+	f.done = true
+	return f.changeset, nil
+}
+
+func (f *filesSource) Len() int {
+	return 1
+}
+
+// NewPatchSeriesSource treats every patch as a changeset, each applied on top of the previous ones
+func NewPatchSeriesSource(patches []string, baseDir string) *PatchSeriesSource {
+	return &PatchSeriesSource{
+		Patches:  patches,
+		BaseDir:  baseDir,
+		contents: map[string]*string{},
+	}
+}
+
+func (p *PatchSeriesSource) Next() (*Changeset, error) {
+	if p.Idx >= len(p.Patches) {
+		return nil, io.EOF
+	}
+
+	patchPath := p.Patches[p.Idx]
+	p.Idx++
+
+	data, err := os.ReadFile(patchPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch %s: %v", patchPath, err)
+	}
+
+	patches, err := parseUnifiedDiff(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse patch %s: %v", patchPath, err)
+	}
+
+	//without the original, a file is only rebuilt from the hunks around its changes, which
+	//the next patch's hunks won't line up with, so only whole files are carried forward
+	whole := map[string]bool{}
+	files, err := patchFiles(patches, func(path string) (*string, error) {
+		content, err := p.lookup(path)
+		whole[path] = content != nil
+		return content, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, f := range files {
+		switch {
+		case patches[i].NewPath == devNull:
+			p.contents[f.FileName] = nil
+		case patches[i].OldPath == devNull || whole[patches[i].OldPath]:
+			content := f.CurrentContent
+			p.contents[f.FileName] = &content
+		default:
+			delete(p.contents, f.FileName)
+		}
+	}
+
+	return &Changeset{
+		ID:      strings.TrimSuffix(filepath.Base(patchPath), filepath.Ext(patchPath)),
+		Message: patchSubject(string(data)),
+		Files:   files,
+	}, nil
+}
+
+func (p *PatchSeriesSource) Len() int {
+	return len(p.Patches)
+}
+
+func (p *PatchSeriesSource) lookup(path string) (*string, error) {
+	if content, ok := p.contents[path]; ok {
+		return content, nil
+	}
+	return readOriginal(p.BaseDir, path)
+}
+
+// NewSnapshotSource treats every subdirectory of dir, in name order, as a full snapshot
+// of the project and yields the changes between consecutive snapshots. Like a commit's
+// parent, the first snapshot is only the starting state, it isn't typed out itself
+func NewSnapshotSource(dir string) (*SnapshotSource, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %v", err)
+	}
+
+	snapshots := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			snapshots = append(snapshots, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	if len(snapshots) < 2 {
+		return nil, fmt.Errorf("found %d snapshots in %s, need at least two to animate between", len(snapshots), dir)
+	}
+
+	return &SnapshotSource{Snapshots: snapshots, Idx: 1}, nil
+}
+
+func (s *SnapshotSource) Next() (*Changeset, error) {
+	//the first snapshot is only ever diffed against
+	s.Idx = max(s.Idx, 1)
+	if s.Idx >= len(s.Snapshots) {
+		return nil, io.EOF
+	}
+
+	prev, err := readSnapshot(s.Snapshots[s.Idx-1])
+	if err != nil {
+		return nil, err
+	}
+
+	curr, err := readSnapshot(s.Snapshots[s.Idx])
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range curr {
+		names = append(names, name)
+	}
+	for name := range prev {
+		if _, ok := curr[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	files := []*CommitFile{}
+	for _, name := range names {
+		if prev[name] == curr[name] {
+			continue
+		}
+		files = append(files, &CommitFile{
+			FileName:       name,
+			PrevContent:    prev[name],
+			CurrentContent: curr[name],
+		})
+	}
+
+	id := filepath.Base(s.Snapshots[s.Idx])
+	s.Idx++
+
+	return &Changeset{
+		ID:    id,
+		Files: files,
+	}, nil
+}
+
+func (s *SnapshotSource) Len() int {
+	return len(s.Snapshots) - 1
+}
+
+func readSnapshot(dir string) (map[string]string, error) {
+	contents := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		contents[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %v", dir, err)
+	}
+
+	return contents, nil
+}
+
+func patchSubject(patch string) string {
+	//git format-patch headers, e.g. "Subject: [PATCH 1/3] Add feature"
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "diff ") || strings.HasPrefix(line, "--- ") {
+			break
+		}
+		if subject, ok := strings.CutPrefix(line, "Subject: "); ok {
+			if strings.HasPrefix(subject, "[") {
+				if _, rest, found := strings.Cut(subject, "] "); found {
+					subject = rest
+				}
+			}
+			return subject
+		}
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package gitanimate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePatches(t *testing.T, patches ...string) []string {
+	dir := t.TempDir()
+	paths := []string{}
+	for i, patch := range patches {
+		path := filepath.Join(dir, fmt.Sprintf("%04d.patch", i+1))
+		if err := os.WriteFile(path, []byte(patch), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestPatchSeriesWithoutBaseDir(t *testing.T) {
+	paths := writePatches(t, `--- a/main.go
++++ b/main.go
+@@ -2,3 +2,3 @@
+ a
+-b
++B
+ c
+`, `--- a/main.go
++++ b/main.go
+@@ -20,3 +20,3 @@
+ x
+-y
++Y
+ z
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++one
++two
+`, `--- a/new.go
++++ b/new.go
+@@ -1,2 +1,2 @@
+ one
+-two
++three
+`)
+
+	want := [][]*CommitFile{
+		{{FileName: "main.go", PrevContent: "a\nb\nc\n", CurrentContent: "a\nB\nc\n"}},
+		{
+			{FileName: "main.go", PrevContent: "x\ny\nz\n", CurrentContent: "x\nY\nz\n"},
+			{FileName: "new.go", PrevContent: "", CurrentContent: "one\ntwo\n"},
+		},
+		{{FileName: "new.go", PrevContent: "one\ntwo\n", CurrentContent: "one\nthree\n"}},
+	}
+
+	src := NewPatchSeriesSource(paths, "")
+	for i, files := range want {
+		changeset, err := src.Next()
+		if err != nil {
+			t.Fatalf("patch %d: %v", i+1, err)
+		}
+		if len(changeset.Files) != len(files) {
+			t.Fatalf("patch %d: got %d files, want %d", i+1, len(changeset.Files), len(files))
+		}
+		for j, f := range files {
+			got := changeset.Files[j]
+			if got.FileName != f.FileName || got.PrevContent != f.PrevContent || got.CurrentContent != f.CurrentContent {
+				t.Errorf("patch %d: got %+v, want %+v", i+1, *got, *f)
+			}
+		}
+	}
+}
+
+func TestSnapshotSourceStartsFromFirst(t *testing.T) {
+	dir := t.TempDir()
+	for snapshot, files := range map[string]map[string]string{
+		"1": {"main.go": "a\n", "old.go": "x\n"},
+		"2": {"main.go": "a\nb\n", "old.go": "x\n"},
+		"3": {"main.go": "a\nb\n"},
+	} {
+		for name, content := range files {
+			path := filepath.Join(dir, snapshot, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	src, err := NewSnapshotSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Len() != 2 {
+		t.Errorf("got %d changesets, want 2", src.Len())
+	}
+
+	want := []CommitFile{
+		{FileName: "main.go", PrevContent: "a\n", CurrentContent: "a\nb\n"},
+		{FileName: "old.go", PrevContent: "x\n"},
+	}
+	for i, f := range want {
+		changeset, err := src.Next()
+		if err != nil {
+			t.Fatalf("changeset %d: %v", i+1, err)
+		}
+		if len(changeset.Files) != 1 {
+			t.Fatalf("changeset %d: got %d files, want 1", i+1, len(changeset.Files))
+		}
+		got := changeset.Files[0]
+		if got.FileName != f.FileName || got.PrevContent != f.PrevContent || got.CurrentContent != f.CurrentContent {
+			t.Errorf("changeset %d: got %+v, want %+v", i+1, *got, f)
+		}
+	}
+	if _, err := src.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last snapshot, got %v", err)
+	}
+}
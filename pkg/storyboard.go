@@ -0,0 +1,325 @@
+package gitanimate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"gopkg.in/yaml.v3"
+)
+
+type storyboardCompiler struct {
+	path    string
+	text    string
+	cursor  int
+	speed   float32
+	caption string
+	steps   []*Segment
+}
+
+// LoadStoryboard compiles a YAML (or JSON) storyboard into a changeset whose files
+// carry pre-computed animation segments, errors point at the offending line
+func LoadStoryboard(path string) (*Changeset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storyboard: %v", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("%s: empty storyboard", path)
+	}
+
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, nodeError(path, doc, "storyboard must be a mapping")
+	}
+
+	changeset := &Changeset{Files: []*CommitFile{}}
+	var filesNode *yaml.Node
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, value := doc.Content[i], doc.Content[i+1]
+		switch key.Value {
+		case "title":
+			if err := value.Decode(&changeset.ID); err != nil {
+				return nil, nodeError(path, value, "title must be a string")
+			}
+			changeset.Message = changeset.ID
+		case "files":
+			filesNode = value
+		default:
+			return nil, nodeError(path, key, "unknown field %q", key.Value)
+		}
+	}
+
+	if filesNode == nil || filesNode.Kind != yaml.SequenceNode || len(filesNode.Content) == 0 {
+		return nil, nodeError(path, doc, "storyboard must list at least one file under \"files\"")
+	}
+
+	for _, fileNode := range filesNode.Content {
+		f, err := compileStoryboardFile(path, fileNode)
+		if err != nil {
+			return nil, err
+		}
+		changeset.Files = append(changeset.Files, f)
+	}
+
+	return changeset, nil
+}
+
+func compileStoryboardFile(path string, node *yaml.Node) (*CommitFile, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nodeError(path, node, "file entry must be a mapping")
+	}
+
+	c := &storyboardCompiler{path: path, speed: 1, steps: []*Segment{}}
+	f := &CommitFile{}
+	var stepsNode *yaml.Node
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		var err error
+		switch key.Value {
+		case "name":
+			err = value.Decode(&f.FileName)
+		case "content":
+			err = value.Decode(&c.text)
+		case "speed":
+			err = c.decodeSpeed(value, &c.speed)
+		case "steps":
+			stepsNode = value
+		default:
+			return nil, nodeError(path, key, "unknown field %q", key.Value)
+		}
+		if err != nil {
+			return nil, nodeError(path, value, "invalid %s: %v", key.Value, err)
+		}
+	}
+
+	if f.FileName == "" {
+		return nil, nodeError(path, node, "file entry is missing a name")
+	}
+	if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+		return nil, nodeError(path, node, "file %s must have a list of steps", f.FileName)
+	}
+
+	f.PrevContent = c.text
+	c.cursor = len(c.text)
+
+	for _, stepNode := range stepsNode.Content {
+		if err := c.compileStep(stepNode); err != nil {
+			return nil, err
+		}
+	}
+
+	f.CurrentContent = c.text
+	f.Segments = c.steps
+	return f, nil
+}
+
+func (c *storyboardCompiler) compileStep(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return nodeError(c.path, node, "step must be a mapping")
+	}
+
+	fields := map[string]*yaml.Node{}
+	var action string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "insert", "delete", "replace", "move", "pause", "caption":
+			if action != "" {
+				return nodeError(c.path, key, "step has both %q and %q, use one action per step", action, key.Value)
+			}
+			action = key.Value
+		case "with", "speed":
+		default:
+			return nodeError(c.path, key, "unknown step field %q", key.Value)
+		}
+		fields[key.Value] = value
+	}
+
+	speed := c.speed
+	if speedNode, ok := fields["speed"]; ok {
+		if err := c.decodeSpeed(speedNode, &speed); err != nil {
+			return nodeError(c.path, speedNode, "invalid speed: %v", err)
+		}
+		if action == "" {
+			//a bare speed step changes the speed of every following step
+			c.speed = speed
+			return nil
+		}
+	}
+
+	if _, ok := fields["with"]; ok && action != "replace" {
+		return nodeError(c.path, fields["with"], "\"with\" is only valid in a replace step")
+	}
+
+	value := fields[action]
+	switch action {
+	case "insert":
+		var text string
+		if err := value.Decode(&text); err != nil || text == "" {
+			return nodeError(c.path, value, "insert must be a non-empty string")
+		}
+		c.edit(c.cursor, "", text, speed)
+	case "delete":
+		var text string
+		if err := value.Decode(&text); err != nil || text == "" {
+			return nodeError(c.path, value, "delete must be a non-empty string")
+		}
+		pos := c.find(text)
+		if pos < 0 {
+			return nodeError(c.path, value, "text to delete %q not found", text)
+		}
+		c.edit(pos, text, "", speed)
+	case "replace":
+		var text, with string
+		if err := value.Decode(&text); err != nil || text == "" {
+			return nodeError(c.path, value, "replace must be a non-empty string")
+		}
+		withNode, ok := fields["with"]
+		if !ok {
+			return nodeError(c.path, node, "replace step is missing \"with\"")
+		}
+		if err := withNode.Decode(&with); err != nil {
+			return nodeError(c.path, withNode, "with must be a string")
+		}
+		pos := c.find(text)
+		if pos < 0 {
+			return nodeError(c.path, value, "text to replace %q not found", text)
+		}
+		c.edit(pos, text, with, speed)
+	case "move":
+		return c.move(value)
+	case "pause":
+		var seconds float32
+		if err := value.Decode(&seconds); err != nil || seconds < 0 {
+			return nodeError(c.path, value, "pause must be a non-negative number of seconds")
+		}
+		c.steps = append(c.steps, &Segment{
+			Diffs:   []diffmatchpatch.Diff{{Type: diffmatchpatch.DiffEqual, Text: c.text}},
+			Speed:   speed,
+			Pause:   seconds,
+			Caption: c.caption,
+		})
+	case "caption":
+		if err := value.Decode(&c.caption); err != nil {
+			return nodeError(c.path, value, "caption must be a string")
+		}
+	default:
+		return nodeError(c.path, node, "step has no action, expected one of insert, delete, replace, move, pause, caption or speed")
+	}
+
+	return nil
+}
+
+func (c *storyboardCompiler) edit(pos int, deleted, inserted string, speed float32) {
+	diffs := []diffmatchpatch.Diff{{Type: diffmatchpatch.DiffEqual, Text: c.text[:pos]}}
+	if deleted != "" {
+		diffs = append(diffs, diffmatchpatch.Diff{Type: diffmatchpatch.DiffDelete, Text: deleted})
+	}
+	if inserted != "" {
+		diffs = append(diffs, diffmatchpatch.Diff{Type: diffmatchpatch.DiffInsert, Text: inserted})
+	}
+	//always end on an equal op, the state machine treats the last op as the end of the animation
+	diffs = append(diffs, diffmatchpatch.Diff{Type: diffmatchpatch.DiffEqual, Text: c.text[pos+len(deleted):]})
+
+	c.text = c.text[:pos] + inserted + c.text[pos+len(deleted):]
+	c.cursor = pos + len(inserted)
+	c.steps = append(c.steps, &Segment{
+		Diffs:   diffs,
+		Speed:   speed,
+		Caption: c.caption,
+	})
+}
+
+// find looks for text after the cursor first, then from the start of the file
+func (c *storyboardCompiler) find(text string) int {
+	if idx := strings.Index(c.text[c.cursor:], text); idx >= 0 {
+		return c.cursor + idx
+	}
+	return strings.Index(c.text, text)
+}
+
+func (c *storyboardCompiler) move(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		switch node.Value {
+		case "start":
+			c.cursor = 0
+		case "end":
+			c.cursor = len(c.text)
+		default:
+			return nodeError(c.path, node, "move must be start, end or a mapping with line/column, after or before")
+		}
+		return nil
+	}
+
+	var target struct {
+		Line   int    `yaml:"line"`
+		Column int    `yaml:"column"`
+		After  string `yaml:"after"`
+		Before string `yaml:"before"`
+	}
+	if err := node.Decode(&target); err != nil {
+		return nodeError(c.path, node, "invalid move: %v", err)
+	}
+
+	switch {
+	case target.After != "":
+		pos := c.find(target.After)
+		if pos < 0 {
+			return nodeError(c.path, node, "text %q not found", target.After)
+		}
+		c.cursor = pos + len(target.After)
+	case target.Before != "":
+		pos := c.find(target.Before)
+		if pos < 0 {
+			return nodeError(c.path, node, "text %q not found", target.Before)
+		}
+		c.cursor = pos
+	case target.Line > 0:
+		lines := strings.SplitAfter(c.text, "\n")
+		if target.Line > len(lines) {
+			return nodeError(c.path, node, "line %d out of range, file has %d lines", target.Line, len(lines))
+		}
+		pos := 0
+		for _, l := range lines[:target.Line-1] {
+			pos += len(l)
+		}
+		//columns count characters as they're seen, a letter and its combining marks are one
+		column := max(target.Column, 1) - 1
+		line := strings.TrimSuffix(lines[target.Line-1], "\n")
+		if n := uniseg.GraphemeClusterCount(line); column > n {
+			return nodeError(c.path, node, "column %d out of range, line %d %q has %d characters", target.Column, target.Line, line, n)
+		}
+		offset := 0
+		for range column {
+			offset = nextGrapheme(line, offset)
+		}
+		c.cursor = pos + graphemeFloor(line, offset)
+	default:
+		return nodeError(c.path, node, "move must be start, end or a mapping with line/column, after or before")
+	}
+
+	return nil
+}
+
+func (c *storyboardCompiler) decodeSpeed(node *yaml.Node, speed *float32) error {
+	if err := node.Decode(speed); err != nil {
+		return err
+	}
+	if *speed <= 0 {
+		return fmt.Errorf("speed must be positive")
+	}
+	return nil
+}
+
+func nodeError(path string, node *yaml.Node, format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", path, node.Line, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,45 @@
+package gitanimate
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStoryboardMoveColumn(t *testing.T) {
+	tests := []struct {
+		move    string
+		want    int
+		wantErr bool
+	}{
+		{move: "{line: 1, column: 2}", want: 2},
+		{move: "{line: 1, column: 3}", want: 5},
+		{move: "{line: 1, column: 4}", want: 8},
+		{move: "{line: 1, column: 5}", wantErr: true},
+		{move: "{line: 2, column: 2}", want: 10},
+	}
+
+	for _, tt := range tests {
+		//é, then 日, then e and a combining acute
+		c := &storyboardCompiler{path: "story.yaml", text: "\u00e9\u65e5e\u0301\nab\n"}
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(tt.move), &node); err != nil {
+			t.Fatal(err)
+		}
+
+		err := c.move(node.Content[0])
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.move)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.move, err)
+			continue
+		}
+		if c.cursor != tt.want {
+			t.Errorf("%s: cursor at %d, want %d", tt.move, c.cursor, tt.want)
+		}
+	}
+}
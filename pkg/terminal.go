@@ -0,0 +1,170 @@
+package gitanimate
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	DefaultTerminalPrompt = "$ "
+
+	//seconds the finished session stays up before cutting to the editor
+	terminalHold = 0.8
+	//mixed into the seed of the scenes' typing model
+	terminalSeedSalt = "\x00terminal"
+)
+
+// terminal is a fake shell session typed out before or after a clip, each command is typed
+// with the clip's terminal typing model and entered once it's done
+type terminal struct {
+	Prompt   string
+	Commands []string
+	Lines    []string
+	Current  int
+	Typed    int
+	Typo     string
+}
+
+// terminalCommands fills {file}, {hash} and {subject} into the command templates for a clip
+func terminalCommands(templates []string, params *AnimateDiffParams) []string {
+	hash, subject := "", ""
+	if params.Commit != nil {
+		hash = params.Commit.ID[:min(7, len(params.Commit.ID))]
+		subject, _, _ = strings.Cut(params.Commit.Message, "\n")
+	}
+	r := strings.NewReplacer("{file}", params.Filename, "{hash}", hash, "{subject}", subject)
+
+	commands := make([]string, len(templates))
+	for i, t := range templates {
+		commands[i] = r.Replace(t)
+	}
+	return commands
+}
+
+// terminalText is everything the clip's terminal scenes show, for loading their glyphs
+func terminalText(params *AnimateDiffParams) string {
+	if len(params.Params.TerminalBefore)+len(params.Params.TerminalAfter) == 0 {
+		return ""
+	}
+	commands := append(terminalCommands(params.Params.TerminalBefore, params), terminalCommands(params.Params.TerminalAfter, params)...)
+	return terminalPrompt(params.Params) + strings.Join(commands, "")
+}
+
+// newTerminalTyping is the typing model for a clip's terminal scenes, seeded apart from the clip's
+// own so adding a scene doesn't change how the code is typed
+func newTerminalTyping(params *AnimateDiffParams) *TypingModel {
+	return NewTypingModel(params.Params, params.Filename+terminalSeedSalt)
+}
+
+func terminalPrompt(params *AnimateParams) string {
+	if params.TerminalPrompt == "" {
+		return DefaultTerminalPrompt
+	}
+	return params.TerminalPrompt
+}
+
+// step types the next key, or enters the command once it's all typed, and returns the delay before the next step.
+// It returns false once every command has been entered
+func (t *terminal) step(typing *TypingModel) (bool, float32) {
+	if t.Current >= len(t.Commands) {
+		return false, 0
+	}
+	command := t.Commands[t.Current]
+
+	switch {
+	case t.Typo != "":
+		t.Typo = ""
+		return true, typing.Delay(0, 0)
+	case t.Typed >= len(command):
+		t.Lines = append(t.Lines, t.Prompt+command)
+		t.Current++
+		t.Typed = 0
+		return t.Current < len(t.Commands), typing.ReactionDelay()
+	}
+
+	next := runeAt(command, t.Typed)
+	if typo, ok := typing.Typo(next); ok {
+		t.Typo = string(typo)
+		return true, typing.ReactionDelay()
+	}
+
+	t.Typed = nextGrapheme(command, t.Typed)
+	if t.Typed >= len(command) {
+		//a beat before hitting enter
+		return true, typing.ReactionDelay()
+	}
+	return true, typing.Delay(runeBefore(command, t.Typed), runeAt(command, t.Typed))
+}
+
+func (t *terminal) render(cursorVisible bool) {
+	rows := append([]string{}, t.Lines...)
+	current := t.Prompt
+	if t.Current < len(t.Commands) {
+		current += t.Commands[t.Current][:t.Typed] + t.Typo
+	}
+	rows = append(rows, current)
+
+	//scroll older commands off the top once the screen is full
+	fit := max(int((canvasHeight-padding*2)/lineHeight), 1)
+	rows = rows[max(len(rows)-fit, 0):]
+
+	promptColor := getColorForTokenType(chroma.Keyword)
+	if style.Get(chroma.GenericPrompt).Colour.IsSet() {
+		promptColor = getColorForTokenType(chroma.GenericPrompt)
+	}
+	textColor := getColorForTokenType(chroma.Text)
+
+	y := padding
+	for i, row := range rows {
+		x := padding
+		if strings.HasPrefix(row, t.Prompt) {
+			drawText(t.Prompt, x, y, promptColor)
+			x += measureText(t.Prompt)
+			row = row[len(t.Prompt):]
+		}
+		drawText(row, x, y, textColor)
+		x += measureText(row)
+
+		if i == len(rows)-1 && cursorVisible {
+			rl.DrawRectangleRec(rl.Rectangle{X: x, Y: y, Width: cellWidth(), Height: lineHeight}, rl.Fade(ui.Cursor, 0.7))
+		}
+		y += lineHeight
+	}
+}
+
+// renderTerminalScene plays a terminal session typing out commands, then holds on it for a moment
+func renderTerminalScene(commands []string, prompt string, typing *TypingModel, bg rl.Color, recorder *frameRecorder) {
+	if len(commands) == 0 {
+		return
+	}
+
+	t := terminal{Prompt: prompt, Commands: commands}
+	timer := typing.ReactionDelay()
+	typingDone := false
+	var elapsed, hold float32
+
+	for frames := 0; !rl.WindowShouldClose() && frames <= MaxFrameCount && hold < terminalHold; frames++ {
+		deltaTime := rl.GetFrameTime()
+		elapsed += deltaTime
+
+		if typingDone {
+			hold += deltaTime
+		} else {
+			timer -= deltaTime
+			for timer <= 0 && !typingDone {
+				more, delay := t.step(typing)
+				typingDone = !more
+				timer += delay
+			}
+		}
+
+		beginFrame(bg)
+		//the cursor blinks while nothing is being typed
+		t.render(!typingDone || int(elapsed*2)%2 == 0)
+		endFrame()
+		recorder.capture()
+		recorder.sceneFrames++
+	}
+}
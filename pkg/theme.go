@@ -0,0 +1,387 @@
+package gitanimate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// uiColors are the editor colours that aren't token styles
+type uiColors struct {
+	Gutter     rl.Color
+	LineNumber rl.Color
+	Cursor     rl.Color
+	Selection  rl.Color
+}
+
+// themeColors are the editor colours a theme file sets, as hex strings, empty when unset
+type themeColors struct {
+	Gutter     string
+	LineNumber string
+	Cursor     string
+	Selection  string
+}
+
+var ui uiColors
+
+// textMateScopes maps TextMate scopes to the chroma tokens they colour, later entries win
+// when a token is listed twice
+var textMateScopes = []struct {
+	Scope string
+	Token chroma.TokenType
+}{
+	{"comment", chroma.Comment},
+	{"string", chroma.LiteralString},
+	{"string.regexp", chroma.LiteralStringRegex},
+	{"constant.character.escape", chroma.LiteralStringEscape},
+	{"constant", chroma.NameConstant},
+	{"constant.numeric", chroma.LiteralNumber},
+	{"constant.language", chroma.KeywordConstant},
+	{"keyword", chroma.Keyword},
+	{"keyword.operator", chroma.Operator},
+	{"storage", chroma.KeywordDeclaration},
+	{"support.type", chroma.KeywordType},
+	{"storage.type", chroma.KeywordType},
+	{"variable", chroma.NameVariable},
+	{"support.function", chroma.NameBuiltin},
+	{"entity.name.function", chroma.NameFunction},
+	{"entity.name.type", chroma.NameClass},
+	{"entity.name.class", chroma.NameClass},
+	{"entity.name.namespace", chroma.NameNamespace},
+	{"entity.name.tag", chroma.NameTag},
+	{"entity.other.attribute-name", chroma.NameAttribute},
+	{"entity.name.function.decorator", chroma.NameDecorator},
+	{"punctuation", chroma.Punctuation},
+	{"markup.heading", chroma.GenericHeading},
+	{"markup.bold", chroma.GenericStrong},
+	{"markup.italic", chroma.GenericEmph},
+	{"markup.inserted", chroma.GenericInserted},
+	{"markup.deleted", chroma.GenericDeleted},
+}
+
+// applyTheme sets the style and editor colours for a clip: a registered chroma style or a theme file,
+// then the colour flags on top
+func applyTheme(params *AnimateParams) {
+	var colors themeColors
+	if styles.Registry[strings.ToLower(params.Theme)] != nil {
+		style = styles.Get(params.Theme)
+	} else {
+		var err error
+		style, colors, err = loadThemeFile(params.Theme)
+		if err != nil {
+			Logger.Errorf("Failed to load theme %s, defaulting to %s: %v", params.Theme, styles.Fallback.Name, err)
+			style = styles.Fallback
+		}
+	}
+
+	ui = uiColors{
+		LineNumber: rl.Gray,
+		Cursor:     rl.White,
+		Selection:  rl.Fade(getColorForTokenType(chroma.Text), 0.3),
+	}
+	entry := style.Get(chroma.LineNumbers)
+	if entry.Colour.IsSet() {
+		ui.LineNumber = getColorForTokenType(chroma.LineNumbers)
+	}
+	if entry.Background.IsSet() && entry.Background != style.Get(chroma.Background).Background {
+		bg := entry.Background
+		ui.Gutter = rl.Color{R: bg.Red(), G: bg.Green(), B: bg.Blue(), A: 255}
+	}
+	if entry := style.Get(chroma.LineHighlight); entry.Background.IsSet() && entry.Background != style.Get(chroma.Background).Background {
+		bg := entry.Background
+		ui.Selection = rl.Color{R: bg.Red(), G: bg.Green(), B: bg.Blue(), A: 255}
+	}
+
+	//the flags beat the theme file
+	overrides := []struct {
+		color  *rl.Color
+		values []string
+		name   string
+	}{
+		{&ui.Gutter, []string{colors.Gutter, params.GutterColor}, "gutter"},
+		{&ui.LineNumber, []string{colors.LineNumber, params.LineNumberColor}, "line number"},
+		{&ui.Cursor, []string{colors.Cursor, params.CursorColor}, "cursor"},
+		{&ui.Selection, []string{colors.Selection, params.SelectionColor}, "selection"},
+	}
+	for _, o := range overrides {
+		for _, value := range o.values {
+			if value == "" {
+				continue
+			}
+			c, err := parseHexColor(value)
+			if err != nil {
+				Logger.Errorf("Ignoring %s colour: %v", o.name, err)
+				continue
+			}
+			*o.color = c
+		}
+	}
+}
+
+// loadThemeFile reads a chroma XML or JSON style, or a VS Code / TextMate theme
+func loadThemeFile(path string) (*chroma.Style, themeColors, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, themeColors{}, fmt.Errorf("failed to read theme: %v", err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		s, err := chroma.NewXMLStyle(bytes.NewReader(data))
+		if err != nil {
+			return nil, themeColors{}, fmt.Errorf("failed to parse chroma style: %v", err)
+		}
+		return s, themeColors{}, nil
+	}
+
+	//VS Code themes are JSON with comments and trailing commas
+	data = stripJSONComments(data)
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, themeColors{}, fmt.Errorf("failed to parse theme: %v", err)
+	}
+	if _, ok := fields["entries"]; ok {
+		s, err := parseChromaJSON(name, data)
+		return s, themeColors{}, err
+	}
+	_, tokenColors := fields["tokenColors"]
+	_, colors := fields["colors"]
+	if tokenColors || colors {
+		return parseVSCodeTheme(name, data)
+	}
+	return nil, themeColors{}, fmt.Errorf("%s is neither a chroma style nor a VS Code theme", path)
+}
+
+// parseChromaJSON reads the JSON form of a chroma style, {"name": ..., "entries": {"Keyword": "bold #ff79c6"}}
+func parseChromaJSON(name string, data []byte) (*chroma.Style, error) {
+	var theme struct {
+		Name    string            `json:"name"`
+		Entries map[string]string `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &theme); err != nil {
+		return nil, fmt.Errorf("failed to parse chroma style: %v", err)
+	}
+	if theme.Name != "" {
+		name = theme.Name
+	}
+
+	entries := chroma.StyleEntries{}
+	for key, value := range theme.Entries {
+		ttype, err := chroma.TokenTypeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("unknown token type %s", key)
+		}
+		entries[ttype] = value
+	}
+	return chroma.NewStyle(name, entries)
+}
+
+type vsCodeTheme struct {
+	Name        string            `json:"name"`
+	Colors      map[string]string `json:"colors"`
+	TokenColors []struct {
+		Scope    json.RawMessage `json:"scope"`
+		Settings struct {
+			Foreground string `json:"foreground"`
+			Background string `json:"background"`
+			FontStyle  string `json:"fontStyle"`
+		} `json:"settings"`
+	} `json:"tokenColors"`
+}
+
+// parseVSCodeTheme turns a VS Code / TextMate theme into a chroma style, each token takes the
+// most specific rule whose scope selects it
+func parseVSCodeTheme(name string, data []byte) (*chroma.Style, themeColors, error) {
+	var theme vsCodeTheme
+	if err := json.Unmarshal(data, &theme); err != nil {
+		return nil, themeColors{}, fmt.Errorf("failed to parse VS Code theme: %v", err)
+	}
+	if theme.Name != "" {
+		name = theme.Name
+	}
+
+	entries := chroma.StyleEntries{}
+	background, foreground := theme.Colors["editor.background"], theme.Colors["editor.foreground"]
+	for _, rule := range theme.TokenColors {
+		//a rule without a scope sets the defaults
+		if len(rule.Scope) > 0 {
+			continue
+		}
+		if background == "" {
+			background = rule.Settings.Background
+		}
+		if foreground == "" {
+			foreground = rule.Settings.Foreground
+		}
+	}
+	bgEntry := []string{}
+	if c := hexColor(background); c != "" {
+		bgEntry = append(bgEntry, "bg:"+c)
+	}
+	if c := hexColor(foreground); c != "" {
+		bgEntry = append(bgEntry, c)
+	}
+	if len(bgEntry) > 0 {
+		entries[chroma.Background] = strings.Join(bgEntry, " ")
+	}
+
+	for _, mapping := range textMateScopes {
+		best := -1
+		var entry []string
+		for _, rule := range theme.TokenColors {
+			for _, scope := range ruleScopes(rule.Scope) {
+				if scope != mapping.Scope && !strings.HasPrefix(mapping.Scope, scope+".") {
+					continue
+				}
+				if len(scope) < best {
+					continue
+				}
+				best = len(scope)
+				entry = nil
+				if c := hexColor(rule.Settings.Foreground); c != "" {
+					entry = append(entry, c)
+				}
+				for _, fontStyle := range strings.Fields(rule.Settings.FontStyle) {
+					switch fontStyle {
+					case "bold", "italic", "underline":
+						entry = append(entry, fontStyle)
+					}
+				}
+			}
+		}
+		if best >= 0 && len(entry) > 0 {
+			entries[mapping.Token] = strings.Join(entry, " ")
+		}
+	}
+
+	s, err := chroma.NewStyle(name, entries)
+	if err != nil {
+		return nil, themeColors{}, fmt.Errorf("failed to build style from VS Code theme: %v", err)
+	}
+
+	colors := themeColors{
+		Gutter:     theme.Colors["editorGutter.background"],
+		LineNumber: theme.Colors["editorLineNumber.foreground"],
+		Cursor:     theme.Colors["editorCursor.foreground"],
+		Selection:  theme.Colors["editor.selectionBackground"],
+	}
+	return s, colors, nil
+}
+
+// ruleScopes lists the selectors of a tokenColors rule, which may be a string of comma separated
+// selectors or an array of them, descendant selectors are skipped
+func ruleScopes(raw json.RawMessage) []string {
+	var list []string
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		list = strings.Split(single, ",")
+	} else if err := json.Unmarshal(raw, &list); err != nil {
+		return nil
+	}
+
+	scopes := []string{}
+	for _, scope := range list {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || strings.Contains(scope, " ") {
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// hexColor normalises a theme colour for chroma, which has no alpha
+func hexColor(value string) string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "#") {
+		return ""
+	}
+	switch len(value) {
+	case 4, 7:
+		return value
+	case 5:
+		return value[:4]
+	case 9:
+		return value[:7]
+	}
+	return ""
+}
+
+// parseHexColor reads #rgb, #rgba, #rrggbb or #rrggbbaa
+func parseHexColor(value string) (rl.Color, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(hex) == 3 || len(hex) == 4 {
+		expanded := make([]byte, 0, len(hex)*2)
+		for i := 0; i < len(hex); i++ {
+			expanded = append(expanded, hex[i], hex[i])
+		}
+		hex = string(expanded)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return rl.Color{}, fmt.Errorf("%s is not a hex colour", value)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return rl.Color{}, fmt.Errorf("%s is not a hex colour", value)
+	}
+	return rl.Color{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+}
+
+// stripJSONComments drops // and /* */ comments and trailing commas outside of strings
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+			continue
+		case c == '}' || c == ']':
+			//drop a comma left dangling before the close
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
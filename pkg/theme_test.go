@@ -0,0 +1,159 @@
+package gitanimate
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestStripJSONComments(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"line comment", "{\"a\": 1 // one\n}", "{\"a\": 1 \n}"},
+		{"block comment", `{/* a */"a": 1}`, `{"a": 1}`},
+		{"slashes in a string", `{"url": "https://example.com//x", "b": "/* no */"}`, `{"url": "https://example.com//x", "b": "/* no */"}`},
+		{"escaped quote", `{"a": "say \"//hi\"" // c` + "\n}", `{"a": "say \"//hi\"" ` + "\n}"},
+		{"trailing commas", "{\"a\": [1, 2,],\n}", "{\"a\": [1, 2]\n}"},
+		{"comma in a string", `{"a": ",]"}`, `{"a": ",]"}`},
+	}
+	for _, tt := range tests {
+		if got := string(stripJSONComments([]byte(tt.in))); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    rl.Color
+		wantErr bool
+	}{
+		{in: "#ff8000", want: rl.Color{R: 0xff, G: 0x80, A: 0xff}},
+		{in: "ff8000", want: rl.Color{R: 0xff, G: 0x80, A: 0xff}},
+		{in: "#f80", want: rl.Color{R: 0xff, G: 0x88, A: 0xff}},
+		{in: "#f808", want: rl.Color{R: 0xff, G: 0x88, A: 0x88}},
+		{in: " #11223344 ", want: rl.Color{R: 0x11, G: 0x22, B: 0x33, A: 0x44}},
+		{in: "#12345", wantErr: true},
+		{in: "#gggggg", wantErr: true},
+		{in: "red", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseHexColor(tt.in)
+		if tt.wantErr != (err != nil) || got != tt.want {
+			t.Errorf("parseHexColor(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestHexColor(t *testing.T) {
+	for in, want := range map[string]string{
+		"#abc":      "#abc",
+		"#abcd":     "#abc",
+		"#aabbcc":   "#aabbcc",
+		"#aabbccdd": "#aabbcc",
+		"aabbcc":    "",
+		"#ab":       "",
+	} {
+		if got := hexColor(in); got != want {
+			t.Errorf("hexColor(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRuleScopes(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{`"comment"`, []string{"comment"}},
+		{`"string, constant.numeric"`, []string{"string", "constant.numeric"}},
+		{`["keyword", "meta.tag string"]`, []string{"keyword"}},
+		{`42`, nil},
+	}
+	for _, tt := range tests {
+		if got := ruleScopes(json.RawMessage(tt.raw)); !slices.Equal(got, tt.want) {
+			t.Errorf("ruleScopes(%s) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseVSCodeTheme(t *testing.T) {
+	theme := `{
+		// comments and trailing commas are fine in VS Code themes
+		"name": "Test",
+		"colors": {
+			"editor.background": "#101010",
+			"editor.foreground": "#e0e0e0",
+			"editorLineNumber.foreground": "#808080",
+			"editorCursor.foreground": "#ff0000",
+		},
+		"tokenColors": [
+			{"scope": "comment", "settings": {"foreground": "#00ff00", "fontStyle": "italic"}},
+			{"scope": "string", "settings": {"foreground": "#0000ff"}},
+			{"scope": "string.regexp", "settings": {"foreground": "#ff00ffcc"}},
+			{"scope": ["keyword", "storage"], "settings": {"foreground": "#ffff00", "fontStyle": "bold underline"}},
+			{"scope": "keyword.operator", "settings": {"foreground": "#00ffff"}},
+		],
+	}`
+
+	style, colors, err := parseVSCodeTheme("fallback", stripJSONComments([]byte(theme)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if style.Name != "Test" {
+		t.Errorf("name %q, want Test", style.Name)
+	}
+
+	tests := []struct {
+		token                   chroma.TokenType
+		colour                  string
+		bold, italic, underline bool
+	}{
+		{chroma.Comment, "#00ff00", false, true, false},
+		{chroma.LiteralString, "#0000ff", false, false, false},
+		//the more specific rule wins, and loses its alpha
+		{chroma.LiteralStringRegex, "#ff00ff", false, false, false},
+		{chroma.Keyword, "#ffff00", true, false, true},
+		{chroma.KeywordDeclaration, "#ffff00", true, false, true},
+		{chroma.Operator, "#00ffff", false, false, false},
+	}
+	for _, tt := range tests {
+		entry := style.Get(tt.token)
+		if entry.Colour.String() != tt.colour {
+			t.Errorf("%s: colour %s, want %s", tt.token, entry.Colour, tt.colour)
+		}
+		if (entry.Bold == chroma.Yes) != tt.bold || (entry.Italic == chroma.Yes) != tt.italic || (entry.Underline == chroma.Yes) != tt.underline {
+			t.Errorf("%s: got %s", tt.token, entry)
+		}
+	}
+
+	if bg := style.Get(chroma.Background); bg.Background.String() != "#101010" || bg.Colour.String() != "#e0e0e0" {
+		t.Errorf("background %s", bg)
+	}
+	want := themeColors{LineNumber: "#808080", Cursor: "#ff0000"}
+	if colors != want {
+		t.Errorf("colours %+v, want %+v", colors, want)
+	}
+}
+
+func TestParseChromaJSON(t *testing.T) {
+	style, err := parseChromaJSON("fallback", []byte(`{"entries": {"Keyword": "bold #ff79c6", "Background": "bg:#282a36"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if style.Name != "fallback" {
+		t.Errorf("name %q, want fallback", style.Name)
+	}
+	if kw := style.Get(chroma.Keyword); kw.Colour.String() != "#ff79c6" || kw.Bold != chroma.Yes {
+		t.Errorf("keyword %s", kw)
+	}
+
+	if _, err := parseChromaJSON("x", []byte(`{"entries": {"NotAToken": "#fff"}}`)); err == nil {
+		t.Error("expected an error for an unknown token type")
+	}
+}
@@ -0,0 +1,207 @@
+package gitanimate
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+	"unicode"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	TypingDistExp       = "exp"
+	TypingDistLogNormal = "lognormal"
+	TypingDistUniform   = "uniform"
+)
+
+// TypingModel decides how long each keystroke takes and when the typist slips up,
+// every random decision comes from a single seeded source so clips are reproducible
+type TypingModel struct {
+	MinDelay     float32
+	MaxDelay     float32
+	Distribution string
+	WordPause    float32
+	TypoRate     float32
+	BurstFactor  float32
+	Fixed        bool
+	rng          *rand.Rand
+}
+
+var (
+	//frequent bigrams in english and code that get typed as a quick burst
+	commonBigrams = map[string]bool{
+		"th": true, "he": true, "in": true, "er": true, "an": true, "re": true, "on": true,
+		"at": true, "en": true, "nd": true, "ti": true, "es": true, "or": true, "te": true,
+		"of": true, "ed": true, "is": true, "it": true, "al": true, "ar": true, "st": true,
+		"to": true, "nt": true, "ng": true, "se": true, "ou": true, "io": true, "le": true,
+		"co": true, "me": true, "de": true, "ro": true, "ne": true, "ra": true, "ce": true,
+		"()": true, "{}": true, "[]": true, "//": true, "==": true, "!=": true, ":=": true,
+		"->": true, "=>": true, "++": true, "&&": true, "||": true,
+	}
+
+	keyboardRows = []string{
+		"1234567890-=",
+		"qwertyuiop[]",
+		"asdfghjkl;'",
+		"zxcvbnm,./",
+	}
+)
+
+// NewTypingModel builds a clip's typing model, an empty or unknown TypingDist (see CheckParams) is exp
+func NewTypingModel(params *AnimateParams, clip string) *TypingModel {
+	//mix the clip name into the seed so every file gets its own, still reproducible, rhythm
+	h := fnv.New64a()
+	h.Write([]byte(clip))
+	seed := params.Seed ^ int64(h.Sum64())
+
+	return &TypingModel{
+		MinDelay:     params.MinDelay,
+		MaxDelay:     params.MaxDelay,
+		Distribution: params.TypingDist,
+		WordPause:    params.WordPause,
+		TypoRate:     params.TypoRate,
+		BurstFactor:  params.BurstFactor,
+		Fixed:        params.DisableRandom,
+		rng:          rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Delay is the time between typing prev and typing next
+func (t *TypingModel) Delay(prev, next rune) float32 {
+	if t.Fixed {
+		return t.MinDelay
+	}
+
+	delay := t.interval()
+
+	if commonBigrams[strings.ToLower(string([]rune{prev, next}))] && t.BurstFactor > 0 {
+		delay = max(delay*t.BurstFactor, t.MinDelay)
+	}
+
+	//hesitate a little before starting the next word
+	if isWordRune(next) && !isWordRune(prev) && prev != 0 {
+		delay += t.random(0.5, 1.5) * t.WordPause
+	}
+
+	return delay
+}
+
+// Typo returns a neighbouring key to mistype instead of next, if the typist fumbles it
+func (t *TypingModel) Typo(next rune) (rune, bool) {
+	if t.Fixed || t.TypoRate <= 0 || !unicode.IsLetter(next) || next > unicode.MaxASCII {
+		return 0, false
+	}
+	if t.rng.Float32() >= t.TypoRate {
+		return 0, false
+	}
+
+	lower := unicode.ToLower(next)
+	for row, keys := range keyboardRows {
+		col := strings.IndexRune(keys, lower)
+		if col < 0 {
+			continue
+		}
+
+		neighbours := []rune{}
+		for _, r := range []int{row - 1, row, row + 1} {
+			if r < 0 || r >= len(keyboardRows) {
+				continue
+			}
+			for _, c := range []int{col - 1, col, col + 1} {
+				if c < 0 || c >= len(keyboardRows[r]) || (r == row && c == col) {
+					continue
+				}
+				if k := rune(keyboardRows[r][c]); unicode.IsLetter(k) {
+					neighbours = append(neighbours, k)
+				}
+			}
+		}
+
+		if len(neighbours) == 0 {
+			return 0, false
+		}
+		typo := neighbours[t.rng.Intn(len(neighbours))]
+		if unicode.IsUpper(next) {
+			typo = unicode.ToUpper(typo)
+		}
+		return typo, true
+	}
+
+	return 0, false
+}
+
+// ReactionDelay is how long it takes to notice a typo before backspacing it
+func (t *TypingModel) ReactionDelay() float32 {
+	return t.MaxDelay * t.random(0.5, 1)
+}
+
+func (t *TypingModel) interval() float32 {
+	switch t.Distribution {
+	case TypingDistLogNormal:
+		median := t.MinDelay + (t.MaxDelay-t.MinDelay)*0.2
+		delay := median * float32(math.Exp(t.rng.NormFloat64()*0.5))
+		return min(max(delay, t.MinDelay), t.MaxDelay)
+	case TypingDistUniform:
+		return t.random(t.MinDelay, t.MaxDelay)
+	}
+
+	delay := float32(math.Exp(float64(t.random(-10, 0))))*(t.MaxDelay-t.MinDelay) + t.MinDelay
+	if delay > 0.9*t.MaxDelay {
+		delay = t.MaxDelay
+	}
+	return delay
+}
+
+func (t *TypingModel) random(min, max float32) float32 {
+	return min + t.rng.Float32()*(max-min)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// keys returns the character just typed and the one about to be typed in the current insert
+func (a *AnimState) keys() (rune, rune) {
+	if a.OpIndex >= len(a.Diffs) || a.Diffs[a.OpIndex].Type != diffmatchpatch.DiffInsert {
+		return 0, 0
+	}
+
+	text := a.Diffs[a.OpIndex].Text
+	var prev, next rune
+	if a.CharIndex > 0 && a.CharIndex <= len(text) {
+		prev = runeBefore(text, a.CharIndex)
+	}
+	if a.CharIndex < len(text) {
+		next = runeAt(text, a.CharIndex)
+	}
+	return prev, next
+}
+
+// stepTypo plays out a typo: the wrong key, a beat to notice, then a backspace.
+// It returns false when there's no typo in progress and the next key should be typed normally
+func (a *AnimState) stepTypo(t *TypingModel) (bool, float32) {
+	switch {
+	case a.Typo != "":
+		a.Typo = ""
+		a.TypoFixed = true
+		return true, t.Delay(0, 0)
+	case a.TypoFixed:
+		a.TypoFixed = false
+		return false, 0
+	}
+
+	//nobody mistypes a block they're about to paste
+	if a.pasting() {
+		return false, 0
+	}
+
+	_, next := a.keys()
+	if typo, ok := t.Typo(next); ok {
+		a.Typo = string(typo)
+		return true, t.ReactionDelay()
+	}
+
+	return false, 0
+}
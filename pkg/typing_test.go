@@ -0,0 +1,67 @@
+package gitanimate
+
+import (
+	"slices"
+	"testing"
+)
+
+func typingRun(params *AnimateParams) ([]float32, []rune) {
+	typing := NewTypingModel(params, "main.go")
+	delays := []float32{}
+	typos := []rune{}
+	text := []rune("func handleRequest(w http.ResponseWriter) { return }")
+	for i, r := range text {
+		prev := rune(0)
+		if i > 0 {
+			prev = text[i-1]
+		}
+		delays = append(delays, typing.Delay(prev, r), typing.ReactionDelay())
+		if typo, ok := typing.Typo(r); ok {
+			typos = append(typos, typo)
+		}
+	}
+	return delays, typos
+}
+
+func TestTypingModelSeed(t *testing.T) {
+	params := &AnimateParams{MinDelay: 0.03, MaxDelay: 0.2, WordPause: 0.1, TypoRate: 0.2, BurstFactor: 0.5, Seed: 42}
+
+	for _, dist := range []string{TypingDistExp, TypingDistLogNormal, TypingDistUniform} {
+		params.TypingDist = dist
+		delays, typos := typingRun(params)
+		again, againTypos := typingRun(params)
+		if !slices.Equal(delays, again) || !slices.Equal(typos, againTypos) {
+			t.Errorf("%s: the same seed gave different runs", dist)
+		}
+		if len(typos) == 0 {
+			t.Errorf("%s: no typos at a typo rate of 0.2", dist)
+		}
+	}
+
+	params.TypingDist = TypingDistExp
+	delays, _ := typingRun(params)
+	params.Seed = 43
+	other, _ := typingRun(params)
+	if slices.Equal(delays, other) {
+		t.Error("different seeds gave the same run")
+	}
+}
+
+func TestTypingModelRange(t *testing.T) {
+	for _, dist := range []string{TypingDistExp, TypingDistLogNormal, TypingDistUniform} {
+		typing := NewTypingModel(&AnimateParams{MinDelay: 0.03, MaxDelay: 0.2, TypingDist: dist, Seed: 7}, "main.go")
+		for range 10000 {
+			if d := typing.interval(); d < typing.MinDelay || d > typing.MaxDelay {
+				t.Fatalf("%s: interval %v outside [%v, %v]", dist, d, typing.MinDelay, typing.MaxDelay)
+			}
+		}
+	}
+
+	fixed := NewTypingModel(&AnimateParams{MinDelay: 0.03, MaxDelay: 0.2, TypoRate: 1, DisableRandom: true}, "main.go")
+	if d := fixed.Delay('a', 'b'); d != fixed.MinDelay {
+		t.Errorf("fixed delay %v, want %v", d, fixed.MinDelay)
+	}
+	if _, ok := fixed.Typo('a'); ok {
+		t.Error("fixed typing made a typo")
+	}
+}
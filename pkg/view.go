@@ -0,0 +1,420 @@
+package gitanimate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/schollz/progressbar/v3"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	ViewType    = "type"
+	ViewSplit   = "split"
+	ViewUnified = "unified"
+)
+
+const (
+	rowEqual = iota
+	rowAdded
+	rowDeleted
+	rowEmpty
+	rowHunk
+)
+
+const (
+	viewContextLines = 3
+	viewHoldTime     = 1.5
+	viewHoldPerLine  = 0.05
+)
+
+// viewRow is one line of a diff view, in the split view Right holds the new side of the row
+type viewRow struct {
+	Kind    int
+	OldLine int
+	NewLine int
+	Tokens  []chroma.Token
+	Header  string
+	Right   *viewRow
+}
+
+type lineEntry struct {
+	Kind    int
+	OldLine int
+	NewLine int
+}
+
+// animateDiffView renders a static split or unified diff, scrolling from hunk to hunk
+func animateDiffView(params *AnimateDiffParams) error {
+	split := params.Params.View == ViewSplit
+
+	rows, hunks, err := buildViewRows(params.PrevContent, currentContent(params), lang(params.Filename), split)
+	if err != nil {
+		return err
+	}
+
+	recorder, err := newFrameRecorder()
+	if err != nil {
+		return err
+	}
+	defer recorder.close()
+	recorder.subtitles = params.Params.Subtitles
+
+	bar := progressbar.NewOptions(len(hunks),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionSetWidth(45),
+		progressbar.OptionSetDescription(params.Filename+fmt.Sprintf(" (%d/%d) ", params.Pos, params.Total)),
+	)
+
+	terminalTyping := newTerminalTyping(params)
+
+	clip := newClipCaptions(params)
+	title := chromeTitle(params)
+	bgRl := openWindow(params.Params, params.ShowWindow, params.PrevContent+currentContent(params)+title+terminalText(params)+clip.text())
+	defer closeWindow()
+	recorder.chrome = newChrome(params.Params, title, bgRl)
+
+	applyTabWidth(params)
+	maxLines := max(lineCount(params.PrevContent), lineCount(currentContent(params)))
+	if params.Params.FitWidth > 0 {
+		//each side of the split view holds the full width
+		columns := params.Params.FitWidth
+		if split {
+			columns *= 2
+		}
+		fitFontSize(columns, maxLines)
+	}
+	numberWidth := lineNumberWidth(maxLines) + fontSize/2
+
+	windowHeight := canvasHeight
+	maxScroll := max(float32(len(rows))*lineHeight-windowHeight+lineHeight, 0)
+	targets := make([]float32, len(hunks))
+	holds := make([]float32, len(hunks))
+	for i, h := range hunks {
+		targets[i] = min(max(float32(h)*lineHeight-windowHeight*0.15, 0), maxScroll)
+		end := len(rows)
+		if i+1 < len(hunks) {
+			end = hunks[i+1]
+		}
+		holds[i] = viewHoldTime + float32(end-h)*viewHoldPerLine
+	}
+
+	hunk := 0
+	var cam camera
+	var timer float32
+	if len(targets) > 0 {
+		cam.jump(targets[0])
+	}
+
+	renderTerminalScene(terminalCommands(params.Params.TerminalBefore, params), terminalPrompt(params.Params), terminalTyping, bgRl, recorder)
+
+	for !rl.WindowShouldClose() {
+		deltaTime := rl.GetFrameTime()
+		cam.update(deltaTime)
+
+		//hold on each hunk once the camera has arrived, then move on to the next
+		if cam.settled() {
+			timer += deltaTime
+		}
+		if hunk < len(hunks) && timer >= holds[hunk] {
+			bar.Set(hunk + 1)
+			if hunk+1 >= len(hunks) {
+				break
+			}
+			hunk++
+			cam.TargetY = targets[hunk]
+			timer = 0
+		}
+
+		beginFrame(bgRl)
+		renderViewRows(rows, split, cam.Y, numberWidth)
+		captions := clip.at(float32(recorder.frameCount) / FrameRate)
+		renderCaptions(captions)
+		recorder.showCaptions(captions)
+		endFrame()
+		recorder.capture()
+
+		if len(hunks) == 0 && timer >= viewHoldTime || recorder.clipFrames() > MaxFrameCount {
+			break
+		}
+	}
+
+	renderTerminalScene(terminalCommands(params.Params.TerminalAfter, params), terminalPrompt(params.Params), terminalTyping, bgRl, recorder)
+
+	if err := recorder.encode(clipPath(params)); err != nil {
+		Logger.Fatal(err)
+	}
+
+	bar.Set(len(hunks))
+	bar.Clear()
+	return nil
+}
+
+// currentContent is the text once every diff (or storyboard segment) has been applied
+func currentContent(params *AnimateDiffParams) string {
+	diffs := params.Diffs
+	if len(params.Segments) > 0 {
+		diffs = params.Segments[len(params.Segments)-1].Diffs
+	}
+	return diffmatchpatch.New().DiffText2(diffs)
+}
+
+func buildViewRows(prev, curr, lng string, split bool) ([]*viewRow, []int, error) {
+	oldTokens, err := tokenizeCode(lng, prev)
+	if err != nil {
+		return nil, nil, err
+	}
+	newTokens, err := tokenizeCode(lng, curr)
+	if err != nil {
+		return nil, nil, err
+	}
+	oldLines := tokensByLine(oldTokens)
+	newLines := tokensByLine(newTokens)
+
+	diffs, err := ComputeDiffs(prev, curr, DiffModeLine)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := []lineEntry{}
+	oldLine, newLine := 1, 1
+	for _, d := range diffs {
+		for range splitLines(d.Text) {
+			switch d.Type {
+			case diffmatchpatch.DiffEqual:
+				entries = append(entries, lineEntry{Kind: rowEqual, OldLine: oldLine, NewLine: newLine})
+				oldLine++
+				newLine++
+			case diffmatchpatch.DiffDelete:
+				entries = append(entries, lineEntry{Kind: rowDeleted, OldLine: oldLine})
+				oldLine++
+			case diffmatchpatch.DiffInsert:
+				entries = append(entries, lineEntry{Kind: rowAdded, NewLine: newLine})
+				newLine++
+			}
+		}
+	}
+
+	//keep changed lines and their context, everything else is folded into hunk headers
+	keep := make([]bool, len(entries))
+	for i, e := range entries {
+		if e.Kind == rowEqual {
+			continue
+		}
+		for j := max(i-viewContextLines, 0); j <= min(i+viewContextLines, len(entries)-1); j++ {
+			keep[j] = true
+		}
+	}
+
+	lineTokens := func(lines [][]chroma.Token, line int) []chroma.Token {
+		if line < 1 || line > len(lines) {
+			return nil
+		}
+		return lines[line-1]
+	}
+
+	rows := []*viewRow{}
+	hunks := []int{}
+	for i := 0; i < len(entries); {
+		if !keep[i] {
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(entries) && keep[end] {
+			end++
+		}
+		hunkEntries := entries[i:end]
+
+		hunks = append(hunks, len(rows))
+		rows = append(rows, &viewRow{Kind: rowHunk, Header: hunkHeader(hunkEntries)})
+
+		for j := 0; j < len(hunkEntries); {
+			e := hunkEntries[j]
+			if !split || e.Kind == rowEqual {
+				row := &viewRow{Kind: e.Kind, OldLine: e.OldLine, NewLine: e.NewLine}
+				if e.Kind == rowAdded {
+					row.Tokens = lineTokens(newLines, e.NewLine)
+				} else {
+					row.Tokens = lineTokens(oldLines, e.OldLine)
+				}
+				if split {
+					row.Right = &viewRow{Kind: rowEqual, NewLine: e.NewLine, Tokens: lineTokens(newLines, e.NewLine)}
+				}
+				rows = append(rows, row)
+				j++
+				continue
+			}
+
+			//pair a run of deleted lines with the added lines that replace them
+			deleted := []lineEntry{}
+			for j < len(hunkEntries) && hunkEntries[j].Kind == rowDeleted {
+				deleted = append(deleted, hunkEntries[j])
+				j++
+			}
+			added := []lineEntry{}
+			for j < len(hunkEntries) && hunkEntries[j].Kind == rowAdded {
+				added = append(added, hunkEntries[j])
+				j++
+			}
+
+			for k := 0; k < max(len(deleted), len(added)); k++ {
+				row := &viewRow{Kind: rowEmpty, Right: &viewRow{Kind: rowEmpty}}
+				if k < len(deleted) {
+					row.Kind = rowDeleted
+					row.OldLine = deleted[k].OldLine
+					row.Tokens = lineTokens(oldLines, deleted[k].OldLine)
+				}
+				if k < len(added) {
+					row.Right.Kind = rowAdded
+					row.Right.NewLine = added[k].NewLine
+					row.Right.Tokens = lineTokens(newLines, added[k].NewLine)
+				}
+				rows = append(rows, row)
+			}
+		}
+
+		i = end
+	}
+
+	return rows, hunks, nil
+}
+
+func hunkHeader(entries []lineEntry) string {
+	oldStart, newStart := 0, 0
+	oldCount, newCount := 0, 0
+	for _, e := range entries {
+		if e.OldLine > 0 {
+			if oldStart == 0 {
+				oldStart = e.OldLine
+			}
+			oldCount++
+		}
+		if e.NewLine > 0 {
+			if newStart == 0 {
+				newStart = e.NewLine
+			}
+			newCount++
+		}
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldCount, newStart, newCount)
+}
+
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// tokensByLine splits a token stream into one slice of tokens per line, without the newlines
+func tokensByLine(tokens []chroma.Token) [][]chroma.Token {
+	lines := [][]chroma.Token{{}}
+	for _, token := range tokens {
+		parts := strings.Split(token.Value, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				lines = append(lines, []chroma.Token{})
+			}
+			if part != "" {
+				lines[len(lines)-1] = append(lines[len(lines)-1], chroma.Token{Type: token.Type, Value: part})
+			}
+		}
+	}
+	return lines
+}
+
+func renderViewRows(rows []*viewRow, split bool, scrollY, numberWidth float32) {
+	screenWidth := canvasWidth
+	screenHeight := canvasHeight
+	startX, startY := padding, padding
+	signWidth := rl.MeasureTextEx(font, "+ ", fontSize, 0).X
+
+	first := max(int((scrollY-startY)/lineHeight)-1, 0)
+	for i := first; i < len(rows); i++ {
+		row := rows[i]
+		y := startY + float32(i)*lineHeight - scrollY
+		if y > screenHeight {
+			break
+		}
+
+		if row.Kind == rowHunk {
+			rl.DrawRectangleRec(rl.Rectangle{X: 0, Y: y, Width: screenWidth, Height: lineHeight}, rl.Fade(ui.Selection, 0.5))
+			rl.DrawTextEx(font, row.Header, rl.Vector2{X: startX, Y: y}, fontSize, 0, getColorForTokenType(chroma.Comment))
+			continue
+		}
+
+		if !split {
+			renderViewCell(row, startX, y, screenWidth-startX, []int{row.OldLine, row.NewLine}, numberWidth, signWidth)
+			continue
+		}
+
+		half := screenWidth / 2
+		renderViewCell(row, startX, y, half-startX, []int{row.OldLine}, numberWidth, signWidth)
+		renderViewCell(row.Right, half+startX, y, half-startX, []int{row.Right.NewLine}, numberWidth, signWidth)
+	}
+
+	if split {
+		rl.DrawRectangleRec(rl.Rectangle{X: screenWidth / 2, Y: 0, Width: 1, Height: screenHeight}, ui.LineNumber)
+	}
+}
+
+func renderViewCell(row *viewRow, x, y, width float32, numbers []int, numberWidth, signWidth float32) {
+	sign := " "
+	switch row.Kind {
+	case rowAdded:
+		sign = "+"
+		rl.DrawRectangleRec(rl.Rectangle{X: x - padding, Y: y, Width: width + padding, Height: lineHeight}, rl.Fade(gutterColor(lineAdded), 0.15))
+	case rowDeleted:
+		sign = "-"
+		rl.DrawRectangleRec(rl.Rectangle{X: x - padding, Y: y, Width: width + padding, Height: lineHeight}, rl.Fade(strikeColor(), 0.15))
+	case rowEmpty:
+		rl.DrawRectangleRec(rl.Rectangle{X: x - padding, Y: y, Width: width + padding, Height: lineHeight}, rl.Fade(ui.LineNumber, 0.08))
+		return
+	}
+
+	if ui.Gutter.A > 0 {
+		rl.DrawRectangleRec(rl.Rectangle{X: x - padding, Y: y, Width: padding + numberWidth*float32(len(numbers)) + signWidth, Height: lineHeight}, ui.Gutter)
+	}
+	for _, n := range numbers {
+		if n > 0 {
+			rl.DrawTextEx(font, fmt.Sprintf("%d", n), rl.Vector2{X: x, Y: y}, fontSize, 0, ui.LineNumber)
+		}
+		x += numberWidth
+		width -= numberWidth
+	}
+
+	rl.DrawTextEx(font, sign, rl.Vector2{X: x, Y: y}, fontSize, 0, ui.LineNumber)
+	x += signWidth
+	width -= signWidth
+
+	//long lines are cut off at the edge of the cell
+	beginScissor(x, y, width, lineHeight)
+	lineX := x
+	for _, token := range row.Tokens {
+		ts := getStyleForTokenType(token.Type)
+		for _, char := range token.Value {
+			charWidth := runeWidth(char)
+			if char == '\t' {
+				charWidth = tabAdvance(x - lineX)
+			}
+			if ts.Background.A > 0 {
+				rl.DrawRectangleRec(rl.Rectangle{X: x, Y: y, Width: charWidth, Height: lineHeight}, ts.Background)
+			}
+			if showWhitespace && (char == ' ' || char == '\t') {
+				renderWhitespaceMarker(char, x, y, charWidth)
+			} else {
+				drawStyledRune(char, x, y, ts.Color, ts.Variant)
+			}
+			if ts.Underline {
+				rl.DrawRectangleRec(rl.Rectangle{X: x, Y: y + fontSize, Width: charWidth, Height: 1}, ts.Color)
+			}
+			x += charWidth
+		}
+	}
+	endScissor()
+}
@@ -0,0 +1,125 @@
+package gitanimate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// describeRow is a row as "<sign><old>,<new> <text>", split rows are the two sides joined by " | "
+func describeRow(row *viewRow) string {
+	if row.Kind == rowHunk {
+		return row.Header
+	}
+	cell := func(r *viewRow) string {
+		if r.Kind == rowEmpty {
+			return "~"
+		}
+		var text strings.Builder
+		for _, token := range r.Tokens {
+			text.WriteString(token.Value)
+		}
+		return fmt.Sprintf("%s%d,%d %s", []string{" ", "+", "-"}[r.Kind], r.OldLine, r.NewLine, text.String())
+	}
+	if row.Right != nil {
+		return cell(row) + " | " + cell(row.Right)
+	}
+	return cell(row)
+}
+
+func TestBuildViewRows(t *testing.T) {
+	numbered := func(from, to int, change map[int]string) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			if s, ok := change[i]; ok {
+				b.WriteString(s)
+			} else {
+				fmt.Fprintf(&b, "l%d\n", i)
+			}
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name       string
+		prev, curr string
+		split      bool
+		want       []string
+		wantHunks  []int
+	}{
+		{
+			name: "changed line, unified",
+			prev: "a\nb\nc\n", curr: "a\nB\nc\n",
+			want:      []string{"@@ -1,3 +1,3 @@", " 1,1 a", "-2,0 b", "+0,2 B", " 3,3 c"},
+			wantHunks: []int{0},
+		},
+		{
+			name: "changed line, split",
+			prev: "a\nb\nc\n", curr: "a\nB\nc\n", split: true,
+			want:      []string{"@@ -1,3 +1,3 @@", " 1,1 a |  0,1 a", "-2,0 b | +0,2 B", " 3,3 c |  0,3 c"},
+			wantHunks: []int{0},
+		},
+		{
+			name: "more deleted than added, split",
+			prev: "a\nb\nc\nd\n", curr: "a\nX\nd\n", split: true,
+			want:      []string{"@@ -1,4 +1,3 @@", " 1,1 a |  0,1 a", "-2,0 b | +0,2 X", "-3,0 c | ~", " 4,3 d |  0,3 d"},
+			wantHunks: []int{0},
+		},
+		{
+			name: "more added than deleted, split",
+			prev: "a\nb\nd\n", curr: "a\nX\nY\nd\n", split: true,
+			want:      []string{"@@ -1,3 +1,4 @@", " 1,1 a |  0,1 a", "-2,0 b | +0,2 X", "~ | +0,3 Y", " 3,4 d |  0,4 d"},
+			wantHunks: []int{0},
+		},
+		{
+			name: "pure insert, split",
+			prev: "a\nb\n", curr: "a\nnew\nb\n", split: true,
+			want:      []string{"@@ -1,2 +1,3 @@", " 1,1 a |  0,1 a", "~ | +0,2 new", " 2,3 b |  0,3 b"},
+			wantHunks: []int{0},
+		},
+		{
+			name: "distant changes are separate hunks, unified",
+			prev: numbered(1, 20, nil),
+			curr: numbered(1, 20, map[int]string{2: "L2\n", 18: "L18\n"}),
+			want: []string{
+				"@@ -1,5 +1,5 @@", " 1,1 l1", "-2,0 l2", "+0,2 L2", " 3,3 l3", " 4,4 l4", " 5,5 l5",
+				"@@ -15,6 +15,6 @@", " 15,15 l15", " 16,16 l16", " 17,17 l17", "-18,0 l18", "+0,18 L18", " 19,19 l19", " 20,20 l20",
+			},
+			wantHunks: []int{0, 7},
+		},
+		{
+			name:  "distant changes are separate hunks, split",
+			prev:  numbered(1, 20, nil),
+			curr:  numbered(1, 20, map[int]string{2: "L2\n", 18: "L18\n"}),
+			split: true,
+			want: []string{
+				"@@ -1,5 +1,5 @@", " 1,1 l1 |  0,1 l1", "-2,0 l2 | +0,2 L2", " 3,3 l3 |  0,3 l3", " 4,4 l4 |  0,4 l4", " 5,5 l5 |  0,5 l5",
+				"@@ -15,6 +15,6 @@", " 15,15 l15 |  0,15 l15", " 16,16 l16 |  0,16 l16", " 17,17 l17 |  0,17 l17", "-18,0 l18 | +0,18 L18", " 19,19 l19 |  0,19 l19", " 20,20 l20 |  0,20 l20",
+			},
+			wantHunks: []int{0, 6},
+		},
+		{
+			name: "no changes",
+			prev: "a\nb\n", curr: "a\nb\n",
+			want: []string{}, wantHunks: []int{},
+		},
+	}
+	for _, tt := range tests {
+		rows, hunks, err := buildViewRows(tt.prev, tt.curr, "text", tt.split)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := []string{}
+		for _, row := range rows {
+			got = append(got, describeRow(row))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+		if !slices.Equal(hunks, tt.wantHunks) {
+			t.Errorf("%s: got hunks %v, want %v", tt.name, hunks, tt.wantHunks)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package gitanimate
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	DefaultFontSize   = 20
+	DefaultLineHeight = 1.2
+	DefaultPadding    = 10
+	DefaultTabWidth   = 4
+)
+
+// viewport is the area code is laid out in, inside the padding, with Gutter wide enough
+// for the line numbers on its left
+type viewport struct {
+	X      float32
+	Y      float32
+	Width  float32
+	Height float32
+	Gutter float32
+}
+
+func newViewport(maxLines int) viewport {
+	return viewport{
+		X:      padding,
+		Y:      padding,
+		Width:  canvasWidth - padding*2 - minimapWidth,
+		Height: canvasHeight - padding*2,
+		Gutter: lineNumberWidth(maxLines) + gutterGap(),
+	}
+}
+
+func (v viewport) textX() float32 {
+	return v.X + v.Gutter
+}
+
+func (v viewport) right() float32 {
+	return v.X + v.Width
+}
+
+func lineNumberWidth(maxLines int) float32 {
+	digits := len(strconv.Itoa(max(maxLines, 1)))
+	return rl.MeasureTextEx(font, strings.Repeat("0", digits), fontSize, 0).X
+}
+
+// space between the line numbers and the code, leaving room for the change markers
+func gutterGap() float32 {
+	return max(fontSize/2, gutterMarkerWidth*4)
+}
+
+func lineCount(text string) int {
+	return strings.Count(text, "\n") + 1
+}
+
+// applyTextParams sets the font size, line height and padding, once the font is loaded
+func applyTextParams(params *AnimateParams) {
+	fontSize = DefaultFontSize
+	if params.FontSize > 0 {
+		fontSize = params.FontSize
+	}
+
+	lineHeight = fontSize * DefaultLineHeight
+	if params.LineHeight > 0 {
+		lineHeight = fontSize * params.LineHeight
+	}
+
+	padding = DefaultPadding
+	if params.Padding >= 0 {
+		padding = params.Padding
+	}
+
+	showWhitespace = params.ShowWhitespace
+
+	//the diff views have no minimap
+	minimapWidth = 0
+	if params.Minimap && (params.View == ViewType || params.View == "") {
+		minimapWidth = DefaultMinimapWidth
+	}
+}
+
+// applyTabWidth picks the tab width for a clip: the tab_width flag, then the repository's .editorconfig files
+func applyTabWidth(params *AnimateDiffParams) {
+	tabWidth = DefaultTabWidth
+	if w := params.Params.EditorConfig.TabWidth(params.Filename); w > 0 {
+		tabWidth = w
+	}
+	if params.Params.TabWidth > 0 {
+		tabWidth = params.Params.TabWidth
+	}
+}
+
+// fitFontSize picks the largest font size that fits columns characters and the line numbers across the window
+func fitFontSize(columns, maxLines int) {
+	digits := len(strconv.Itoa(max(maxLines, 1)))
+	//monospace advances scale linearly with the font size
+	advance := rl.MeasureTextEx(font, "0", 100, 0).X / 100
+	width := canvasWidth - padding*2 - minimapWidth
+	chars := advance * float32(columns+digits)
+
+	size := width / (chars + 0.5)
+	if size/2 < gutterMarkerWidth*4 {
+		size = (width - gutterMarkerWidth*4) / chars
+	}
+	//round down so the last column doesn't wrap
+	size = float32(math.Floor(float64(size)*2) / 2)
+
+	ratio := lineHeight / fontSize
+	fontSize = max(size, 1)
+	lineHeight = fontSize * ratio
+}